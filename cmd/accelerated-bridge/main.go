@@ -23,6 +23,11 @@ func setupLogger() {
 func main() {
 	setupLogger()
 	p := plugin.NewPlugin()
-	skel.PluginMain(p.CmdAdd, p.CmdCheck, p.CmdDel,
-		version.All, "")
+	skel.PluginMainFuncs(skel.CNIFuncs{
+		Add:    p.CmdAdd,
+		Del:    p.CmdDel,
+		Check:  p.CmdCheck,
+		GC:     p.CmdGC,
+		Status: p.CmdStatus,
+	}, version.All, "")
 }
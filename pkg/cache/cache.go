@@ -3,6 +3,7 @@ package cache
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 )
@@ -12,6 +13,12 @@ var (
 	CacheDir = "/var/lib/cni/accelerated-bridge"
 )
 
+// journalDir is a subdirectory of the cache directory used for journals
+const journalDir = "journal"
+
+// lockFileSuffix is a suffix of lock files which are stored in the cache directory, e.g. uplink VLAN lock
+const lockFileSuffix = ".lock"
+
 type StateRef string
 
 type StateCache interface {
@@ -23,6 +30,12 @@ type StateCache interface {
 	Load(ref StateRef, state interface{}) error
 	// Delete state from cache
 	Delete(ref StateRef) error
+	// List references to all states in cache
+	List() ([]StateRef, error)
+	// Get State reference identifier for the journal of the device
+	GetJournalRef(deviceID string) StateRef
+	// List references to all journals in cache
+	ListJournals() ([]StateRef, error)
 }
 
 // Create a new state Cache that will Save/Load state
@@ -46,12 +59,12 @@ func (sc *FsStateCache) Save(ref StateRef, state interface{}) error {
 		return err
 	}
 
-	if err = sc.fsOps.MkdirAll(sc.basePath, 0700); err != nil {
-		return fmt.Errorf("failed to create data cache directory(%q): %v", sc.basePath, err)
-	}
-
 	path := filepath.Join(sc.basePath, sRef)
 
+	if err = sc.fsOps.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create data cache directory(%q): %v", filepath.Dir(path), err)
+	}
+
 	err = sc.fsOps.WriteFile(path, bytes, 0600)
 	if err != nil {
 		return fmt.Errorf("failed to write cache data in the path(%q): %v", path, err)
@@ -78,3 +91,35 @@ func (sc *FsStateCache) Delete(ref StateRef) error {
 	}
 	return nil
 }
+
+func (sc *FsStateCache) List() ([]StateRef, error) {
+	return sc.listDir("")
+}
+
+func (sc *FsStateCache) GetJournalRef(deviceID string) StateRef {
+	return StateRef(filepath.Join(journalDir, deviceID))
+}
+
+func (sc *FsStateCache) ListJournals() ([]StateRef, error) {
+	return sc.listDir(journalDir)
+}
+
+// listDir returns references to all files in the subdirectory of the cache directory
+func (sc *FsStateCache) listDir(subdir string) ([]StateRef, error) {
+	dirPath := filepath.Join(sc.basePath, subdir)
+	files, err := sc.fsOps.ReadDir(dirPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cache directory(%q): %v", dirPath, err)
+	}
+	refs := make([]StateRef, 0, len(files))
+	for _, f := range files {
+		if f.IsDir() || strings.HasSuffix(f.Name(), lockFileSuffix) {
+			continue
+		}
+		refs = append(refs, StateRef(filepath.Join(subdir, f.Name())))
+	}
+	return refs, nil
+}
@@ -80,4 +80,64 @@ var _ = Describe("Cache - Simple marshall-able state-object cache", func() {
 			})
 		})
 	})
+
+	Describe("List States", func() {
+		Context("Cache directory does not exist", func() {
+			It("Should return empty list", func() {
+				refs, err := stateCache.List()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(refs).To(BeEmpty())
+			})
+		})
+		Context("Cache has saved states", func() {
+			It("Should return all saved states", func() {
+				savedState := myTestState{FirstState: "first", SecondState: 42}
+				sRef1 := stateCache.GetStateRef("mynet", "cid1", "net1")
+				sRef2 := stateCache.GetStateRef("mynet", "cid2", "net1")
+				Expect(stateCache.Save(sRef1, &savedState)).Should(Succeed())
+				Expect(stateCache.Save(sRef2, &savedState)).Should(Succeed())
+				refs, err := stateCache.List()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(refs).To(ConsistOf(sRef1, sRef2))
+			})
+		})
+		Context("Cache directory has lock files", func() {
+			It("Should skip lock files", func() {
+				savedState := myTestState{FirstState: "first", SecondState: 42}
+				sRef := stateCache.GetStateRef("mynet", "cid1", "net1")
+				Expect(stateCache.Save(sRef, &savedState)).Should(Succeed())
+				Expect(fs.WriteFile(path.Join(CacheDir, "vlan-uplink.lock"), nil, 0600)).Should(Succeed())
+				refs, err := stateCache.List()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(refs).To(ConsistOf(sRef))
+			})
+		})
+	})
+
+	Describe("Journals", func() {
+		Context("Get journal reference", func() {
+			It("Should return journal/<deviceID>", func() {
+				Expect(stateCache.GetJournalRef("0000:af:06.1")).To(
+					BeEquivalentTo(path.Join("journal", "0000:af:06.1")))
+			})
+		})
+		Context("Cache has saved journals and states", func() {
+			It("Should list journals and states separately", func() {
+				savedState := myTestState{FirstState: "first", SecondState: 42}
+				sRef := stateCache.GetStateRef("mynet", "cid1", "net1")
+				jRef := stateCache.GetJournalRef("0000:af:06.1")
+				Expect(stateCache.Save(sRef, &savedState)).Should(Succeed())
+				Expect(stateCache.Save(jRef, &savedState)).Should(Succeed())
+				loadedState := myTestState{}
+				Expect(stateCache.Load(jRef, &loadedState)).Should(Succeed())
+				Expect(loadedState).Should(Equal(savedState))
+				refs, err := stateCache.List()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(refs).To(ConsistOf(sRef))
+				refs, err = stateCache.ListJournals()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(refs).To(ConsistOf(jRef))
+			})
+		})
+	})
 })
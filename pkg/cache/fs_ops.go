@@ -22,6 +22,8 @@ type FileSystemOps interface {
 	Remove(name string) error
 	// Equvalent to os.Stat(...)
 	Stat(name string) (os.FileInfo, error)
+	// Equivalent to ioutil.ReadDir(...)
+	ReadDir(dirname string) ([]os.FileInfo, error)
 }
 
 type stdFileSystemOps struct{}
@@ -46,6 +48,22 @@ func (sfs *stdFileSystemOps) Stat(name string) (os.FileInfo, error) {
 	return os.Stat(name)
 }
 
+func (sfs *stdFileSystemOps) ReadDir(dirname string) ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(dirname)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
 // Fake fileSystemOps used for Unit testing
 func newFakeFileSystemOps() FileSystemOps {
 	return &fakeFileSystemOps{fakefs: afero.Afero{Fs: afero.NewMemMapFs()}}
@@ -74,3 +92,7 @@ func (ffs *fakeFileSystemOps) Remove(name string) error {
 func (ffs *fakeFileSystemOps) Stat(name string) (os.FileInfo, error) {
 	return ffs.fakefs.Stat(name)
 }
+
+func (ffs *fakeFileSystemOps) ReadDir(dirname string) ([]os.FileInfo, error) {
+	return ffs.fakefs.ReadDir(dirname)
+}
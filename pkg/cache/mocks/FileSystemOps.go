@@ -27,6 +27,29 @@ func (_m *FileSystemOps) MkdirAll(path string, perm fs.FileMode) error {
 	return r0
 }
 
+// ReadDir provides a mock function with given fields: dirname
+func (_m *FileSystemOps) ReadDir(dirname string) ([]fs.FileInfo, error) {
+	ret := _m.Called(dirname)
+
+	var r0 []fs.FileInfo
+	if rf, ok := ret.Get(0).(func(string) []fs.FileInfo); ok {
+		r0 = rf(dirname)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]fs.FileInfo)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(dirname)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ReadFile provides a mock function with given fields: filename
 func (_m *FileSystemOps) ReadFile(filename string) ([]byte, error) {
 	ret := _m.Called(filename)
@@ -26,6 +26,20 @@ func (_m *StateCache) Delete(ref cache.StateRef) error {
 	return r0
 }
 
+// GetJournalRef provides a mock function with given fields: deviceID
+func (_m *StateCache) GetJournalRef(deviceID string) cache.StateRef {
+	ret := _m.Called(deviceID)
+
+	var r0 cache.StateRef
+	if rf, ok := ret.Get(0).(func(string) cache.StateRef); ok {
+		r0 = rf(deviceID)
+	} else {
+		r0 = ret.Get(0).(cache.StateRef)
+	}
+
+	return r0
+}
+
 // GetStateRef provides a mock function with given fields: network, cid, ifname
 func (_m *StateCache) GetStateRef(network string, cid string, ifname string) cache.StateRef {
 	ret := _m.Called(network, cid, ifname)
@@ -40,6 +54,52 @@ func (_m *StateCache) GetStateRef(network string, cid string, ifname string) cac
 	return r0
 }
 
+// List provides a mock function with given fields:
+func (_m *StateCache) List() ([]cache.StateRef, error) {
+	ret := _m.Called()
+
+	var r0 []cache.StateRef
+	if rf, ok := ret.Get(0).(func() []cache.StateRef); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]cache.StateRef)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ListJournals provides a mock function with given fields:
+func (_m *StateCache) ListJournals() ([]cache.StateRef, error) {
+	ret := _m.Called()
+
+	var r0 []cache.StateRef
+	if rf, ok := ret.Get(0).(func() []cache.StateRef); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]cache.StateRef)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Load provides a mock function with given fields: ref, state
 func (_m *StateCache) Load(ref cache.StateRef, state interface{}) error {
 	ret := _m.Called(ref, state)
@@ -3,8 +3,11 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
 	"strings"
 
+	"github.com/containernetworking/cni/pkg/version"
+
 	localtypes "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
 	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils"
 )
@@ -13,9 +16,28 @@ const (
 	DefaultBridge = "cni0"
 )
 
+// limits of STP attributes of the bridge port, BR_MAX_PATH_COST and BR_MAX_PORT_PRIORITY in the kernel
+const (
+	maxPortCost     = 65535
+	maxPortPriority = 63
+)
+
+// sysctlIfPrefixes is the allow-list of interface-scoped sysctl prefixes,
+// the prefix must be followed by the name of a single parameter
+var sysctlIfPrefixes = []string{
+	"net.ipv4.conf." + localtypes.IfNamePlaceholder + ".",
+	"net.ipv4.neigh." + localtypes.IfNamePlaceholder + ".",
+	"net.ipv6.conf." + localtypes.IfNamePlaceholder + ".",
+	"net.ipv6.neigh." + localtypes.IfNamePlaceholder + ".",
+}
+
+// sysctlParamRegexp matches the name of the interface-scoped sysctl parameter
+var sysctlParamRegexp = regexp.MustCompile(`^[a-z0-9_]+$`)
+
 type Loader interface {
 	LoadConf(bytes []byte, netConf *localtypes.NetConf) error
 	ParseConf(bytes []byte, conf *localtypes.PluginConf) error
+	ReconstructConf(bytes []byte, conf *localtypes.PluginConf) error
 }
 
 // NewConfig create and initialize Config struct
@@ -42,6 +64,79 @@ func (c *Config) LoadConf(bytes []byte, netConf *localtypes.NetConf) error {
 
 // ParseConf load, parses and validates data from stdin to PluginConf object
 func (c *Config) ParseConf(bytes []byte, conf *localtypes.PluginConf) error {
+	if err := c.parseConf(bytes, conf); err != nil {
+		return err
+	}
+
+	// prevResult is set when the plugin is chained after other plugins
+	if err := version.ParsePrevResult(&conf.NetConf.NetConf); err != nil {
+		return fmt.Errorf("failed to parse prevResult: %v", err)
+	}
+
+	// Assuming VF is netdev interface; Get interface name
+	hostIFName, err := utils.GetVFLinkName(conf.DeviceID)
+	if err != nil || hostIFName == "" {
+		conf.IsUserspaceDriver, err = utils.HasUserspaceDriver(conf.DeviceID)
+		if err != nil {
+			return fmt.Errorf("failed to detect if VF %s has userspace driver %q", conf.DeviceID, err)
+		}
+		if !conf.IsUserspaceDriver {
+			return fmt.Errorf("the VF %s does not have a interface name or a userspace driver", conf.DeviceID)
+		}
+		if conf.Trust != "" {
+			return fmt.Errorf("trust option is not supported for the VF %s with userspace driver", conf.DeviceID)
+		}
+		if conf.Promisc != "" || conf.Allmulti != "" {
+			return fmt.Errorf("promisc and allmulti options are not supported for the VF %s with userspace driver",
+				conf.DeviceID)
+		}
+		if conf.Ethtool != nil {
+			return fmt.Errorf("ethtool option is not supported for the VF %s with userspace driver", conf.DeviceID)
+		}
+		if len(conf.Sysctl) > 0 {
+			return fmt.Errorf("sysctl option is not supported for the VF %s with userspace driver", conf.DeviceID)
+		}
+		if len(conf.VlanInterfaces) > 0 {
+			return fmt.Errorf("vlanInterfaces option is not supported for the VF %s with userspace driver",
+				conf.DeviceID)
+		}
+		// effective MAC of the VF with userspace driver is not known, static FDB entries use the configured MAC
+		if conf.StaticFDB && conf.MAC == "" && conf.RuntimeConfig.Mac == "" {
+			return fmt.Errorf("staticFdb option requires mac for the VF %s with userspace driver", conf.DeviceID)
+		}
+	}
+
+	conf.OrigVfState.HostIFName = hostIFName
+
+	return nil
+}
+
+// ReconstructConf load, parses and validates data from stdin to PluginConf object
+// for the VF which may already be moved to a container network namespace.
+// Used to recover PluginConf when cached state is not available.
+func (c *Config) ReconstructConf(bytes []byte, conf *localtypes.PluginConf) error {
+	if err := c.parseConf(bytes, conf); err != nil {
+		return err
+	}
+
+	hostIFName, err := utils.GetVFLinkName(conf.DeviceID)
+	if err == nil && hostIFName != "" {
+		conf.OrigVfState.HostIFName = hostIFName
+		return nil
+	}
+
+	// VF netdev is not in the host network namespace, VF is either
+	// in a container network namespace or has userspace driver
+	conf.IsUserspaceDriver, err = utils.HasUserspaceDriver(conf.DeviceID)
+	if err != nil {
+		return fmt.Errorf("failed to detect if VF %s has userspace driver %q", conf.DeviceID, err)
+	}
+	return nil
+}
+
+// parseConf load, parses and validates data from stdin to PluginConf object,
+// VF netdev related information is not handled by this function
+func (c *Config) parseConf(bytes []byte, conf *localtypes.PluginConf) error {
 	if err := c.LoadConf(bytes, &conf.NetConf); err != nil {
 		return err
 	}
@@ -66,19 +161,51 @@ func (c *Config) ParseConf(bytes []byte, conf *localtypes.PluginConf) error {
 		return err
 	}
 
-	// Assuming VF is netdev interface; Get interface name
-	hostIFName, err := utils.GetVFLinkName(conf.DeviceID)
-	if err != nil || hostIFName == "" {
-		conf.IsUserspaceDriver, err = utils.HasUserspaceDriver(conf.DeviceID)
-		if err != nil {
-			return fmt.Errorf("failed to detect if VF %s has userspace driver %q", conf.DeviceID, err)
-		}
-		if !conf.IsUserspaceDriver {
-			return fmt.Errorf("the VF %s does not have a interface name or a userspace driver", conf.DeviceID)
-		}
+	if err = validateOnOff("spoofchk", conf.SpoofChk); err != nil {
+		return err
 	}
 
-	conf.OrigVfState.HostIFName = hostIFName
+	if err = validateOnOff("trust", conf.Trust); err != nil {
+		return err
+	}
+
+	if err = validateOnOff("promisc", conf.Promisc); err != nil {
+		return err
+	}
+
+	if err = validateOnOff("allmulti", conf.Allmulti); err != nil {
+		return err
+	}
+
+	if err = validateTxRate(conf.MinTxRate, conf.MaxTxRate); err != nil {
+		return err
+	}
+
+	switch conf.LinkState {
+	case "", "auto", "enable", "disable":
+	default:
+		return fmt.Errorf("linkState %q invalid: value must be \"auto\", \"enable\" or \"disable\"", conf.LinkState)
+	}
+
+	if err = validateEthtool(conf.Ethtool); err != nil {
+		return err
+	}
+
+	if err = validateSysctl(conf.Sysctl); err != nil {
+		return err
+	}
+
+	if err = validatePortFlags(conf.PortFlags, conf.StaticFDB); err != nil {
+		return err
+	}
+
+	if err = validateSTP(conf.Cost, conf.Priority); err != nil {
+		return err
+	}
+
+	if conf.LockTimeout < 0 {
+		return fmt.Errorf("lockTimeout %d invalid: value must not be negative", conf.LockTimeout)
+	}
 
 	// validate vlan id range
 	if conf.Vlan < 0 || conf.Vlan > 4094 {
@@ -93,6 +220,134 @@ func (c *Config) ParseConf(bytes []byte, conf *localtypes.PluginConf) error {
 		}
 	}
 
+	if err = validateVlanInterfaces(conf.VlanInterfaces, conf.Trunk); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateOnOff checks that optional on/off option has a valid value
+func validateOnOff(name, value string) error {
+	if value != "" && value != "on" && value != "off" {
+		return fmt.Errorf("%s %q invalid: value must be \"on\" or \"off\"", name, value)
+	}
+	return nil
+}
+
+// validateTxRate checks that optional VF transmit rates are not negative
+// and minimal rate does not exceed maximal rate, maximal rate 0 means no limit
+func validateTxRate(minTxRate, maxTxRate *int) error {
+	if minTxRate != nil && *minTxRate < 0 {
+		return fmt.Errorf("minTxRate %d invalid: value must not be negative", *minTxRate)
+	}
+	if maxTxRate != nil && *maxTxRate < 0 {
+		return fmt.Errorf("maxTxRate %d invalid: value must not be negative", *maxTxRate)
+	}
+	if minTxRate != nil && maxTxRate != nil && *maxTxRate != 0 && *minTxRate > *maxTxRate {
+		return fmt.Errorf("minTxRate %d invalid: value must not exceed maxTxRate %d", *minTxRate, *maxTxRate)
+	}
+	return nil
+}
+
+// validateEthtool checks that optional ethtool settings have valid feature names,
+// positive channel count and ring sizes
+func validateEthtool(ethConf *localtypes.EthtoolConf) error {
+	if ethConf == nil {
+		return nil
+	}
+	for name := range ethConf.Features {
+		if name == "" {
+			return fmt.Errorf("ethtool feature name must not be empty")
+		}
+	}
+	if ethConf.CombinedChannels != nil && *ethConf.CombinedChannels <= 0 {
+		return fmt.Errorf("ethtool combinedChannels %d invalid: value must be positive", *ethConf.CombinedChannels)
+	}
+	if ethConf.Rings != nil {
+		if ethConf.Rings.RX != nil && *ethConf.Rings.RX <= 0 {
+			return fmt.Errorf("ethtool rings rx %d invalid: value must be positive", *ethConf.Rings.RX)
+		}
+		if ethConf.Rings.TX != nil && *ethConf.Rings.TX <= 0 {
+			return fmt.Errorf("ethtool rings tx %d invalid: value must be positive", *ethConf.Rings.TX)
+		}
+	}
+	return nil
+}
+
+// validateSysctl checks that sysctl keys are interface-scoped keys from the allow-list
+// which use the interface name placeholder
+func validateSysctl(sysctls map[string]string) error {
+	for key := range sysctls {
+		valid := false
+		for _, prefix := range sysctlIfPrefixes {
+			if strings.HasPrefix(key, prefix) && sysctlParamRegexp.MatchString(strings.TrimPrefix(key, prefix)) {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("sysctl %q invalid: key must be one of %s followed by a parameter name",
+				key, strings.Join(sysctlIfPrefixes, ", "))
+		}
+	}
+	return nil
+}
+
+// validatePortFlags checks that bridge port flags of the representor do not prevent the VF
+// from receiving traffic: the bridge can't forward unicast traffic to the port with disabled
+// learning without a static FDB entry for the VF MAC
+func validatePortFlags(flags *localtypes.PortFlags, staticFDB bool) error {
+	if flags == nil {
+		return nil
+	}
+	if flags.Learning != nil && !*flags.Learning && !staticFDB {
+		return fmt.Errorf("portFlags learning invalid: learning can't be disabled if staticFdb option is not set")
+	}
+	return nil
+}
+
+// validateSTP checks that optional STP cost and priority of the bridge port are in the range accepted by the kernel
+func validateSTP(cost, priority *int) error {
+	if cost != nil && (*cost < 1 || *cost > maxPortCost) {
+		return fmt.Errorf("cost %d invalid: value must be in the range 1-%d", *cost, maxPortCost)
+	}
+	if priority != nil && (*priority < 0 || *priority > maxPortPriority) {
+		return fmt.Errorf("priority %d invalid: value must be in the range 0-%d", *priority, maxPortPriority)
+	}
+	return nil
+}
+
+// validateVlanInterfaces checks that VLAN sub-interfaces use unique VLAN IDs allowed by the trunk,
+// have unique names and valid IPAM configuration
+func validateVlanInterfaces(vlanIfs []localtypes.VlanInterface, trunk []int) error {
+	ids := make(map[int]bool, len(vlanIfs))
+	names := make(map[string]bool, len(vlanIfs))
+	for i := range vlanIfs {
+		vlanIf := &vlanIfs[i]
+		if vlanIDIsOutOfRange(vlanIf.ID) {
+			return fmt.Errorf("vlanInterfaces id %d invalid: value must be in the range 1-4094", vlanIf.ID)
+		}
+		if !vlanIDInTrunk(vlanIf.ID, trunk) {
+			return fmt.Errorf("vlanInterfaces id %d invalid: VLAN must be allowed by the trunk option", vlanIf.ID)
+		}
+		if ids[vlanIf.ID] {
+			return fmt.Errorf("vlanInterfaces id %d invalid: value must be unique", vlanIf.ID)
+		}
+		ids[vlanIf.ID] = true
+		// names are compared as templates, the container interface name is not known yet
+		name := vlanIf.IfName(localtypes.IfNamePlaceholder)
+		if strings.ContainsAny(name, "/ \t\n") {
+			return fmt.Errorf("vlanInterfaces name %q invalid: value must not contain '/' or whitespaces", name)
+		}
+		if names[name] {
+			return fmt.Errorf("vlanInterfaces name %q invalid: value must be unique", name)
+		}
+		names[name] = true
+		if vlanIf.IPAM != nil && vlanIf.IPAMType() == "" {
+			return fmt.Errorf("vlanInterfaces ipam for VLAN %d invalid: type must be set", vlanIf.ID)
+		}
+	}
 	return nil
 }
 
@@ -125,17 +380,9 @@ func (c *Config) handleBridgeConfig(conf *localtypes.PluginConf) error {
 	if conf.Bridge == "" {
 		conf.Bridge = DefaultBridge
 	}
-	bridgeNamesInConf := strings.Split(conf.Bridge, ",")
-	allowedBridgeNames := make([]string, 0, len(bridgeNamesInConf))
-	for _, brName := range bridgeNamesInConf {
-		brName = strings.TrimSpace(brName)
-		if brName == "" {
-			return fmt.Errorf("bridge configuration option has invalid format")
-		}
-		allowedBridgeNames = append(allowedBridgeNames, brName)
-	}
-	if len(allowedBridgeNames) == 0 {
-		return fmt.Errorf("bridge configuration option has invalid format")
+	allowedBridgeNames, err := GetBridgeNames(conf.Bridge)
+	if err != nil {
+		return err
 	}
 
 	if len(allowedBridgeNames) == 1 {
@@ -168,3 +415,21 @@ func (c *Config) handleBridgeConfig(conf *localtypes.PluginConf) error {
 	}
 	return nil
 }
+
+// GetBridgeNames returns a list of bridge names from bridge configuration option.
+// If the option is empty, list will contain only DefaultBridge.
+func GetBridgeNames(bridge string) ([]string, error) {
+	if bridge == "" {
+		return []string{DefaultBridge}, nil
+	}
+	bridgeNamesInConf := strings.Split(bridge, ",")
+	bridgeNames := make([]string, 0, len(bridgeNamesInConf))
+	for _, brName := range bridgeNamesInConf {
+		brName = strings.TrimSpace(brName)
+		if brName == "" {
+			return nil, fmt.Errorf("bridge configuration option has invalid format")
+		}
+		bridgeNames = append(bridgeNames, brName)
+	}
+	return bridgeNames, nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	current "github.com/containernetworking/cni/pkg/types/100"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/stretchr/testify/mock"
@@ -86,6 +87,282 @@ var _ = Describe("Config", func() {
 					Expect(err).NotTo(HaveOccurred())
 				})
 			})
+			Context("prevResult checks", func() {
+				It("Valid configuration - prevResult is parsed", func() {
+					data := []byte(`{
+						"cniVersion": "1.0.0",
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"prevResult": {
+							"cniVersion": "1.0.0",
+							"interfaces": [{"name": "eth0", "sandbox": "/proc/1/ns/net"}],
+							"ips": [{"address": "10.0.0.2/24", "interface": 0}]
+						}
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).NotTo(HaveOccurred())
+					Expect(pluginConf.PrevResult).NotTo(BeNil())
+					result, err := current.NewResultFromResult(pluginConf.PrevResult)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(result.Interfaces).To(HaveLen(1))
+					Expect(result.IPs).To(HaveLen(1))
+				})
+				It("Invalid configuration - broken prevResult", func() {
+					data := []byte(`{
+						"cniVersion": "1.0.0",
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"prevResult": {"cniVersion": "1.0.0", "ips": [{"address": "invalid"}]}
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).To(HaveOccurred())
+				})
+			})
+			Context("VF config checks", func() {
+				It("Valid configuration - spoofchk", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"spoofchk": "on"
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).NotTo(HaveOccurred())
+					Expect(pluginConf.SpoofChk).To(Equal("on"))
+				})
+				It("Valid configuration - trust", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"trust": "off"
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).NotTo(HaveOccurred())
+					Expect(pluginConf.Trust).To(Equal("off"))
+				})
+				It("Invalid configuration - trust", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"trust": "true"
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).To(HaveOccurred())
+				})
+				It("Invalid configuration - trust for VF with userspace driver", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.2",
+						"trust": "on"
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).To(MatchError(ContainSubstring("userspace driver")))
+				})
+				It("Valid configuration - ethtool", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"ethtool": {
+							"features": {"rx-gro": false, "tx-tcp-segmentation": true},
+							"combinedChannels": 4,
+							"rings": {"rx": 1024}
+						}
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).NotTo(HaveOccurred())
+					Expect(pluginConf.Ethtool.Features).To(Equal(map[string]bool{
+						"rx-gro": false, "tx-tcp-segmentation": true}))
+					Expect(*pluginConf.Ethtool.CombinedChannels).To(Equal(4))
+					Expect(*pluginConf.Ethtool.Rings.RX).To(Equal(1024))
+					Expect(pluginConf.Ethtool.Rings.TX).To(BeNil())
+				})
+				It("Invalid configuration - ethtool combined channels", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"ethtool": {"combinedChannels": 0}
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).To(HaveOccurred())
+				})
+				It("Invalid configuration - ethtool ring size", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"ethtool": {"rings": {"tx": -1}}
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).To(HaveOccurred())
+				})
+				It("Invalid configuration - ethtool for VF with userspace driver", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.2",
+						"ethtool": {"features": {"rx-gro": false}}
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).To(MatchError(ContainSubstring("userspace driver")))
+				})
+				It("Valid configuration - sysctl", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"sysctl": {
+							"net.ipv6.conf.<if>.disable_ipv6": "0",
+							"net.ipv6.conf.<if>.accept_ra": "2",
+							"net.ipv4.neigh.<if>.gc_stale_time": "120"
+						}
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).NotTo(HaveOccurred())
+					Expect(pluginConf.Sysctl).To(HaveLen(3))
+				})
+				It("Invalid configuration - sysctl which is not interface-scoped", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"sysctl": {"net.ipv4.ip_forward": "1"}
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).To(HaveOccurred())
+				})
+				It("Invalid configuration - sysctl for another interface", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"sysctl": {"net.ipv4.conf.all.forwarding": "1"}
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).To(HaveOccurred())
+				})
+				It("Invalid configuration - sysctl with path traversal", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"sysctl": {"net.ipv4.conf.<if>.../../kernel/core_pattern": "x"}
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).To(HaveOccurred())
+				})
+				It("Invalid configuration - sysctl for VF with userspace driver", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.2",
+						"sysctl": {"net.ipv6.conf.<if>.accept_ra": "0"}
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).To(MatchError(ContainSubstring("userspace driver")))
+				})
+				It("Valid configuration - promisc and allmulti", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"promisc": "on",
+						"allmulti": "off"
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).NotTo(HaveOccurred())
+					Expect(pluginConf.Promisc).To(Equal("on"))
+					Expect(pluginConf.Allmulti).To(Equal("off"))
+				})
+				It("Invalid configuration - allmulti", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"allmulti": "yes"
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).To(HaveOccurred())
+				})
+				It("Invalid configuration - promisc for VF with userspace driver", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.2",
+						"promisc": "on"
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).To(MatchError(ContainSubstring("userspace driver")))
+				})
+				It("Valid configuration - tx rate", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"minTxRate": 100,
+						"maxTxRate": 1000
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).NotTo(HaveOccurred())
+					Expect(*pluginConf.MinTxRate).To(Equal(100))
+					Expect(*pluginConf.MaxTxRate).To(Equal(1000))
+				})
+				It("Valid configuration - min tx rate without max limit", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"minTxRate": 100,
+						"maxTxRate": 0
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).NotTo(HaveOccurred())
+				})
+				It("Invalid configuration - min tx rate exceeds max tx rate", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"minTxRate": 1000,
+						"maxTxRate": 100
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).To(HaveOccurred())
+				})
+				It("Invalid configuration - negative max tx rate", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"maxTxRate": -1
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).To(HaveOccurred())
+				})
+				It("Valid configuration - link state", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"linkState": "enable"
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).NotTo(HaveOccurred())
+					Expect(pluginConf.LinkState).To(Equal("enable"))
+				})
+				It("Invalid configuration - link state", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"linkState": "up"
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).To(HaveOccurred())
+				})
+				It("Invalid configuration - spoofchk", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"spoofchk": "enabled"
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).To(HaveOccurred())
+				})
+			})
+			Context("Lock config checks", func() {
+				It("Invalid configuration - negative lockTimeout", func() {
+					data := []byte(`{
+						"name": "mynet",
+						"type": "accelerated-bridge",
+						"deviceID": "0000:af:06.1",
+						"lockTimeout": -1
+					}`)
+					Expect(conf.ParseConf(data, pluginConf)).To(HaveOccurred())
+				})
+			})
 			Context("VLAN config checks", func() {
 				It("Valid configuration - complex trunk config", func() {
 					data := []byte(`{
@@ -150,6 +427,172 @@ var _ = Describe("Config", func() {
 					err := conf.ParseConf(data, pluginConf)
 					Expect(err).To(HaveOccurred())
 				})
+				It("Valid configuration - bridge port flags", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"portFlags": { "learning": true, "flood": false, "mcastFlood": false, "bcastFlood": true }
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(*pluginConf.PortFlags.Flood).To(BeFalse())
+					Expect(*pluginConf.PortFlags.BcastFlood).To(BeTrue())
+				})
+				It("Valid configuration - learning disabled with static FDB entry", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"staticFdb": true,
+							"portFlags": { "learning": false, "flood": false }
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(pluginConf.StaticFDB).To(BeTrue())
+				})
+				It("Invalid configuration - static FDB for VF with userspace driver without MAC", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.2",
+							"staticFdb": true
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(MatchError(ContainSubstring("userspace driver")))
+				})
+				It("Valid configuration - static FDB for VF with userspace driver with MAC", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.2",
+							"staticFdb": true,
+							"runtimeConfig": { "mac": "c2:11:22:33:44:55" }
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(pluginConf.IsUserspaceDriver).To(BeTrue())
+				})
+				It("Invalid configuration - learning disabled without static FDB entry", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"portFlags": { "learning": false }
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(MatchError(ContainSubstring("learning")))
+				})
+				It("Valid configuration - STP port settings", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"bpduGuard": true,
+							"rootBlock": true,
+							"cost": 100,
+							"priority": 0
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(pluginConf.BpduGuard).To(BeTrue())
+					Expect(pluginConf.RootBlock).To(BeTrue())
+					Expect(*pluginConf.Cost).To(Equal(100))
+					Expect(*pluginConf.Priority).To(Equal(0))
+				})
+				It("Invalid configuration - STP cost out of range", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"cost": 0
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(MatchError(ContainSubstring("cost 0 invalid")))
+				})
+				It("Invalid configuration - STP priority out of range", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"priority": 64
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(MatchError(ContainSubstring("priority 64 invalid")))
+				})
+				It("Valid configuration - VLAN sub-interfaces", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"trunk" : [ { "minID" : 100, "maxID" : 200 } ],
+							"vlanInterfaces": [
+								{ "id": 100 },
+								{ "id": 200, "name": "<if>-v200", "ipam": { "type": "host-local" } }
+							]
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(pluginConf.VlanInterfaces).To(HaveLen(2))
+					Expect(pluginConf.VlanInterfaces[0].IfName("net1")).To(Equal("net1.100"))
+					Expect(pluginConf.VlanInterfaces[1].IfName("net1")).To(Equal("net1-v200"))
+					Expect(pluginConf.VlanInterfaces[1].IPAMType()).To(Equal("host-local"))
+				})
+				It("Invalid configuration - VLAN sub-interface for VLAN which is not in trunk", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"trunk" : [ { "minID" : 100, "maxID" : 200 } ],
+							"vlanInterfaces": [ { "id": 300 } ]
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(MatchError(ContainSubstring("trunk")))
+				})
+				It("Invalid configuration - duplicate VLAN sub-interface", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"trunk" : [ { "minID" : 100, "maxID" : 200 } ],
+							"vlanInterfaces": [ { "id": 100 }, { "id": 100, "name": "vlan100" } ]
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+				It("Invalid configuration - VLAN sub-interface name conflict", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"trunk" : [ { "minID" : 100, "maxID" : 200 } ],
+							"vlanInterfaces": [ { "id": 100, "name": "<if>.200" }, { "id": 200 } ]
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+				It("Invalid configuration - VLAN sub-interface ipam without type", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.1",
+							"trunk" : [ { "id" : 100 } ],
+							"vlanInterfaces": [ { "id": 100, "ipam": { "subnet": "10.0.0.0/24" } } ]
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(HaveOccurred())
+				})
+				It("Invalid configuration - VLAN sub-interfaces for VF with userspace driver", func() {
+					data := []byte(`{
+							"name": "mynet",
+							"type": "accelerated-bridge",
+							"deviceID": "0000:af:06.2",
+							"trunk" : [ { "id" : 100 } ],
+							"vlanInterfaces": [ { "id": 100 } ]
+							}`)
+					err := conf.ParseConf(data, pluginConf)
+					Expect(err).To(MatchError(ContainSubstring("userspace driver")))
+				})
 			})
 			Context("Bridge config checks", func() {
 				configFmt := `{
@@ -225,6 +668,27 @@ var _ = Describe("Config", func() {
 		})
 	})
 
+	Context("Checking ReconstructConf function", func() {
+		BeforeEach(func() {
+			mockSriovnet.On("GetUplinkRepresentor", mock.MatchedBy(func(pciAddr string) bool {
+				return strings.HasPrefix(pciAddr, existingVfPrefix)
+			})).Return(existingPF, nil)
+		})
+		It("VF netdev is in the host namespace", func() {
+			data := []byte(`{
+					"name": "mynet",
+					"type": "accelerated-bridge",
+					"deviceID": "0000:af:06.1",
+					"vlan": 100
+				}`)
+			Expect(conf.ReconstructConf(data, pluginConf)).NotTo(HaveOccurred())
+			Expect(pluginConf.PFName).To(Equal(existingPF))
+			Expect(pluginConf.VFID).To(Equal(1))
+			Expect(pluginConf.OrigVfState.HostIFName).To(Equal("enp175s7"))
+			Expect(pluginConf.IsUserspaceDriver).To(BeFalse())
+		})
+	})
+
 	Context("Checking getVfInfo function", func() {
 		It("Assuming existing PF", func() {
 			mockSriovnet.On("GetUplinkRepresentor", mock.MatchedBy(func(pciAddr string) bool {
@@ -240,4 +704,17 @@ var _ = Describe("Config", func() {
 			Expect(err).To(HaveOccurred())
 		})
 	})
+
+	Context("Checking GetBridgeNames function", func() {
+		It("Empty option - default bridge", func() {
+			Expect(GetBridgeNames("")).To(Equal([]string{DefaultBridge}))
+		})
+		It("Multiple bridges", func() {
+			Expect(GetBridgeNames("br1, br2")).To(Equal([]string{"br1", "br2"}))
+		})
+		It("Invalid format", func() {
+			_, err := GetBridgeNames("br1,,br2")
+			Expect(err).To(HaveOccurred())
+		})
+	})
 })
@@ -39,3 +39,17 @@ func (_m *Loader) ParseConf(bytes []byte, conf *types.PluginConf) error {
 
 	return r0
 }
+
+// ReconstructConf provides a mock function with given fields: bytes, conf
+func (_m *Loader) ReconstructConf(bytes []byte, conf *types.PluginConf) error {
+	ret := _m.Called(bytes, conf)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]byte, *types.PluginConf) error); ok {
+		r0 = rf(bytes, conf)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
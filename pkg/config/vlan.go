@@ -66,6 +66,12 @@ func splitVlanIds(trunks []types.Trunk) ([]int, error) {
 	return vlanIds, nil
 }
 
+// check that vlanID is one of the trunk VLANs, trunk must be sorted
+func vlanIDInTrunk(vlanID int, trunk []int) bool {
+	i := sort.SearchInts(trunk, vlanID)
+	return i < len(trunk) && trunk[i] == vlanID
+}
+
 // check that vlanID is in range 1-4094
 // reserved VLANs (0, 4095) can't be set on Linux bridge
 func vlanIDIsOutOfRange(vlanID int) bool {
@@ -1,16 +1,24 @@
 package manager
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
 
+	"github.com/Mellanox/sriovnet"
+	current "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/gofrs/flock"
 	"github.com/rs/zerolog/log"
 	"github.com/vishvananda/netlink"
 	nl "github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
 
 	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
 	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils"
@@ -18,12 +26,38 @@ import (
 
 const (
 	vlanUplinkLockFile = "/var/lib/cni/accelerated-bridge/vlan-uplink.lock"
+	deviceLockDir      = "/var/lib/cni/accelerated-bridge/locks"
+	lockRetryDelay     = 100 * time.Millisecond
+	// VF administrative MAC is not set by default
+	defaultVfAdminMAC = "00:00:00:00:00:00"
+	// VF spoof checking is enabled by default
+	defaultVfSpoofChk = true
+	// VF trust mode is disabled by default
+	defaultVfTrust = false
+	// VF transmit rate is not limited by default
+	defaultVfTxRate = 0
+	// VF link state follows the PF link state by default
+	defaultVfLinkState = netlink.VF_LINK_STATE_AUTO
 )
 
+// vfLinkStates maps linkState configuration option values to VF link states
+var vfLinkStates = map[string]uint32{
+	"auto":    netlink.VF_LINK_STATE_AUTO,
+	"enable":  netlink.VF_LINK_STATE_ENABLE,
+	"disable": netlink.VF_LINK_STATE_DISABLE,
+}
+
+// ErrLockTimeout is returned when lock was not acquired before timeout expired
+var ErrLockTimeout = errors.New("timed out waiting for lock")
+
+// ErrStateMismatch is returned by CheckRepresentor and CheckVF when the attachment doesn't match its configuration
+var ErrStateMismatch = errors.New("attachment doesn't match configuration")
+
 // IPCLock provides a way to lock and unlock around critical sections given each CNI instance
 // runs as a separate process.  Currently this is with a lockfile.
 type IPCLock interface {
 	Lock() error
+	LockWithTimeout(timeout time.Duration) error
 	Unlock() error
 }
 
@@ -39,32 +73,74 @@ func NewIPCLock(lockfile string) IPCLock {
 	}
 }
 
+// NewDeviceLock returns an instance of IPCLock for the device with provided name,
+// e.g. VF PCI address or representor name
+func NewDeviceLock(name string) IPCLock {
+	return NewIPCLock(filepath.Join(deviceLockDir, name+".lock"))
+}
+
 func (l ipclock) Lock() error {
-	dirpath := filepath.Dir(l.lock.Path())
-	if err := os.MkdirAll(dirpath, 0700); err != nil {
-		return fmt.Errorf("failed to create lock directory(%q): %v", dirpath, err)
+	if err := l.createLockDir(); err != nil {
+		return err
 	}
 
 	return l.lock.Lock()
 }
 
+// LockWithTimeout tries to acquire the lock until timeout expires,
+// ErrLockTimeout is returned if the lock was not acquired
+func (l ipclock) LockWithTimeout(timeout time.Duration) error {
+	if err := l.createLockDir(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	locked, err := l.lock.TryLockContext(ctx, lockRetryDelay)
+	if errors.Is(err, context.DeadlineExceeded) || (err == nil && !locked) {
+		return ErrLockTimeout
+	}
+	return err
+}
+
 func (l ipclock) Unlock() error {
 	return l.lock.Unlock()
 }
 
+func (l ipclock) createLockDir() error {
+	dirpath := filepath.Dir(l.lock.Path())
+	if err := os.MkdirAll(dirpath, 0700); err != nil {
+		return fmt.Errorf("failed to create lock directory(%q): %v", dirpath, err)
+	}
+	return nil
+}
+
 // Manager provides interface invoke sriov nic related operations
 type Manager interface {
 	SetupVF(conf *types.PluginConf, podifName string, cid string, netns ns.NetNS) (string, error)
 	ReleaseVF(conf *types.PluginConf, podifName string, cid string, netns ns.NetNS) error
+	RestoreHostVF(conf *types.PluginConf) error
 	ResetVFConfig(conf *types.PluginConf) error
+	ResolveRepresentor(conf *types.PluginConf) error
+	SaveState(conf *types.PluginConf) error
 	ApplyVFConfig(conf *types.PluginConf) error
 	AttachRepresentor(conf *types.PluginConf) error
+	AddRepresentorFDB(conf *types.PluginConf, mac string) error
+	AddBridgeNeighbors(conf *types.PluginConf, mac string, ips []net.IP) error
 	DetachRepresentor(conf *types.PluginConf) error
+	CheckRepresentor(conf *types.PluginConf) error
+	GetHostInterfaces(conf *types.PluginConf) ([]*current.Interface, error)
+	CheckVF(conf *types.PluginConf, podifName string, result *current.Result, netns ns.NetNS) error
+	CheckStatus(conf *types.NetConf, bridges []string) error
+	RecoverState(conf *types.PluginConf, podifName string, netns ns.NetNS) error
 }
 
 type manager struct {
 	nLink          utils.Netlink
 	sriov          utils.SriovnetProvider
+	ethtool        utils.Ethtool
+	sysctl         utils.Sysctl
 	vlanUplinkLock IPCLock
 }
 
@@ -73,11 +149,13 @@ func NewManager() Manager {
 	return &manager{
 		nLink:          &utils.NetlinkWrapper{},
 		sriov:          &utils.SriovnetWrapper{},
+		ethtool:        &utils.EthtoolWrapper{},
+		sysctl:         &utils.SysctlWrapper{},
 		vlanUplinkLock: NewIPCLock(vlanUplinkLockFile),
 	}
 }
 
-// SetupVF sets up a VF in Pod netns
+// SetupVF sets up a VF in Pod netns, the original VF netdevice state should be saved by SaveState
 func (m *manager) SetupVF(conf *types.PluginConf, podifName, cid string, netns ns.NetNS) (string, error) {
 	linkName := conf.OrigVfState.HostIFName
 
@@ -108,9 +186,6 @@ func (m *manager) SetupVF(conf *types.PluginConf, podifName, cid string, netns n
 			return "", fmt.Errorf("failed to parse MAC address %s: %v", conf.MAC, err)
 		}
 
-		// Save the original effective MAC address before overriding it
-		conf.OrigVfState.EffectiveMAC = linkObj.Attrs().HardwareAddr.String()
-
 		if err = m.nLink.LinkSetHardwareAddr(linkObj, hwaddr); err != nil {
 			return "", fmt.Errorf("failed to set netlink MAC address to %s: %v", hwaddr, err)
 		}
@@ -118,12 +193,10 @@ func (m *manager) SetupVF(conf *types.PluginConf, podifName, cid string, netns n
 
 	// 4. Set MTU
 	if conf.MTU != 0 {
-		prevMTU := linkObj.Attrs().MTU
 		if err = m.nLink.LinkSetMTU(linkObj, conf.MTU); err != nil {
 			return "", fmt.Errorf("failed to set MTU on VF %s: %v", linkObj.Attrs().Name, err)
 		}
 		log.Info().Msgf("VF link %s MTU set to %d", linkObj.Attrs().Name, conf.MTU)
-		conf.OrigVfState.MTU = prevMTU
 	}
 
 	// 5. Change netns
@@ -137,12 +210,35 @@ func (m *manager) SetupVF(conf *types.PluginConf, podifName, cid string, netns n
 			return fmt.Errorf("error setting container interface name %s for %s", linkName, tempName)
 		}
 
-		// 7. Bring IF up in Pod netns
+		// 7. Apply ethtool settings
+		if err := m.setEthtoolConf(podifName, conf.Ethtool); err != nil {
+			return err
+		}
+
+		// 8. Apply sysctls
+		if err := m.applySysctl(conf, podifName); err != nil {
+			return err
+		}
+
+		// 9. Set promiscuous and all-multicast modes
+		if conf.Promisc != "" {
+			if err := m.setPromisc(linkObj, conf.Promisc == "on"); err != nil {
+				return fmt.Errorf("failed to set promiscuous mode %s on VF %s: %v", conf.Promisc, podifName, err)
+			}
+		}
+		if conf.Allmulti != "" {
+			if err := m.setAllmulti(linkObj, conf.Allmulti == "on"); err != nil {
+				return fmt.Errorf("failed to set all-multicast mode %s on VF %s: %v", conf.Allmulti, podifName, err)
+			}
+		}
+
+		// 10. Bring IF up in Pod netns
 		if err := m.nLink.LinkSetUp(linkObj); err != nil {
 			return fmt.Errorf("error bringing interface up in container ns: %q", err)
 		}
 
-		return nil
+		// 11. Create VLAN sub-interfaces
+		return m.addVlanInterfaces(conf, linkObj, podifName)
 	}); err != nil {
 		return "", fmt.Errorf("error setting up interface in container namespace: %q", err)
 	}
@@ -163,56 +259,324 @@ func (m *manager) ReleaseVF(conf *types.PluginConf, podifName, cid string, netns
 			len(conf.ContIFNames), len(conf.OrigVfState.HostIFName))
 	}
 
-	return netns.Do(func(_ ns.NetNS) error {
+	err = netns.Do(func(_ ns.NetNS) error {
 		// get VF device
 		linkObj, err := m.nLink.LinkByName(podifName)
 		if err != nil {
 			return fmt.Errorf("failed to get netlink device with name %s: %q", podifName, err)
 		}
 
-		// shutdown VF device
-		if err = m.nLink.LinkSetDown(linkObj); err != nil {
-			return fmt.Errorf("failed to set link %s down: %q", podifName, err)
+		if err = m.deleteVlanInterfaces(conf); err != nil {
+			return err
+		}
+
+		if err = m.restoreVFConfig(conf, linkObj); err != nil {
+			return err
+		}
+
+		return m.moveVFToInitNS(conf, linkObj, initns)
+	})
+	if err != nil {
+		return err
+	}
+
+	// VF device is always down after it was moved to another netns
+	if !conf.OrigVfState.NetdevSaved || !conf.OrigVfState.AdminUp {
+		return nil
+	}
+	linkObj, err := m.nLink.LinkByName(conf.OrigVfState.HostIFName)
+	if err != nil {
+		return fmt.Errorf("failed to get netlink device with name %s: %q", conf.OrigVfState.HostIFName, err)
+	}
+	return m.restoreVFAdminState(conf, linkObj)
+}
+
+// moveVFToInitNS moves VF device to init netns. VF which original name is unknown keeps its current name,
+// it is renamed to a temporary name only if the name is used by another link in init netns
+func (m *manager) moveVFToInitNS(conf *types.PluginConf, linkObj netlink.Link, initns ns.NetNS) error {
+	err := m.nLink.LinkSetNsFd(linkObj, int(initns.Fd()))
+	if err != nil && conf.OrigVfState.HostIFName == "" && errors.Is(err, unix.EEXIST) {
+		tempName := fmt.Sprintf("%s%d", "temp_", linkObj.Attrs().Index)
+		log.Info().Msgf("VF name %s is used in init netns, renaming VF to %s", linkObj.Attrs().Name, tempName)
+		if err = m.nLink.LinkSetName(linkObj, tempName); err != nil {
+			return fmt.Errorf("failed to rename link %s to %s: %v", linkObj.Attrs().Name, tempName, err)
+		}
+		err = m.nLink.LinkSetNsFd(linkObj, int(initns.Fd()))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to move interface %s to init netns: %v", linkObj.Attrs().Name, err)
+	}
+	return nil
+}
+
+// RestoreHostVF restores original name and configuration of the VF which was returned
+// to the host network namespace by the kernel after container network namespace was destroyed
+func (m *manager) RestoreHostVF(conf *types.PluginConf) error {
+	linkName, err := utils.GetVFLinkName(conf.DeviceID)
+	if err != nil {
+		return fmt.Errorf("failed to find VF %s netdevice in host namespace: %v", conf.DeviceID, err)
+	}
+
+	linkObj, err := m.nLink.LinkByName(linkName)
+	if err != nil {
+		return fmt.Errorf("failed to get netlink device with name %s: %q", linkName, err)
+	}
+
+	if err = m.restoreVFConfig(conf, linkObj); err != nil {
+		return err
+	}
+
+	return m.restoreVFAdminState(conf, linkObj)
+}
+
+// restoreVFConfig restores original name, effective MAC, MTU and flags of the VF,
+// options which were not configured are restored only if the complete VF netdevice state was saved.
+// Name, effective MAC and MTU are not restored if their original values are unknown, e.g. state was recovered
+func (m *manager) restoreVFConfig(conf *types.PluginConf, linkObj netlink.Link) error {
+	linkName := linkObj.Attrs().Name
+	attrs := linkObj.Attrs()
+	orig := conf.OrigVfState
+
+	// shutdown VF device
+	if err := m.nLink.LinkSetDown(linkObj); err != nil {
+		return fmt.Errorf("failed to set link %s down: %q", linkName, err)
+	}
+
+	// rename VF device
+	hostIFName := linkName
+	if orig.HostIFName != "" {
+		hostIFName = orig.HostIFName
+		if err := m.nLink.LinkSetName(linkObj, hostIFName); err != nil {
+			return fmt.Errorf("failed to rename link %s to host name %s: %q", linkName, hostIFName, err)
 		}
+	}
+
+	// reset ethtool settings
+	if err := m.setEthtoolConf(hostIFName, orig.Ethtool); err != nil {
+		return err
+	}
 
-		// rename VF device
-		err = m.nLink.LinkSetName(linkObj, conf.OrigVfState.HostIFName)
+	// reset effective MAC address
+	if orig.EffectiveMAC != "" &&
+		(conf.MAC != "" || orig.NetdevSaved && attrs.HardwareAddr.String() != orig.EffectiveMAC) {
+		hwaddr, err := net.ParseMAC(conf.OrigVfState.EffectiveMAC)
 		if err != nil {
-			return fmt.Errorf("failed to rename link %s to host name %s: %q",
-				podifName, conf.OrigVfState.HostIFName, err)
+			return fmt.Errorf("failed to parse original effective MAC address %s: %v",
+				conf.OrigVfState.EffectiveMAC, err)
 		}
 
-		// reset effective MAC address
-		if conf.MAC != "" {
-			var hwaddr net.HardwareAddr
-			hwaddr, err = net.ParseMAC(conf.OrigVfState.EffectiveMAC)
-			if err != nil {
-				return fmt.Errorf("failed to parse original effective MAC address %s: %v",
-					conf.OrigVfState.EffectiveMAC, err)
-			}
+		if err = m.nLink.LinkSetHardwareAddr(linkObj, hwaddr); err != nil {
+			return fmt.Errorf("failed to restore original effective netlink MAC address %s: %v",
+				hwaddr, err)
+		}
+	}
 
-			if err = m.nLink.LinkSetHardwareAddr(linkObj, hwaddr); err != nil {
-				return fmt.Errorf("failed to restore original effective netlink MAC address %s: %v",
-					hwaddr, err)
-			}
+	// reset MTU
+	if orig.MTU != 0 && (conf.MTU != 0 || orig.NetdevSaved && attrs.MTU != orig.MTU) {
+		if err := m.nLink.LinkSetMTU(linkObj, conf.OrigVfState.MTU); err != nil {
+			return fmt.Errorf("failed to set MTU on VF %s: %v", linkObj.Attrs().Name, err)
+		}
+		log.Info().Msgf("VF link %s MTU set to %d", linkObj.Attrs().Name, conf.OrigVfState.MTU)
+	}
+
+	// reset promiscuous mode
+	if conf.Promisc != "" || orig.NetdevSaved && (attrs.Promisc == 1) != orig.Promisc {
+		if err := m.setPromisc(linkObj, orig.Promisc); err != nil {
+			return fmt.Errorf("failed to restore promiscuous mode on VF %s: %v", linkName, err)
 		}
+	}
+
+	// reset all-multicast mode
+	if conf.Allmulti != "" || orig.NetdevSaved && (attrs.Allmulti == 1) != orig.Allmulti {
+		if err := m.setAllmulti(linkObj, orig.Allmulti); err != nil {
+			return fmt.Errorf("failed to restore all-multicast mode on VF %s: %v", linkName, err)
+		}
+	}
+
+	return nil
+}
+
+// setPromisc turns promiscuous mode of the link on or off
+func (m *manager) setPromisc(linkObj netlink.Link, on bool) error {
+	if on {
+		return m.nLink.LinkSetPromiscOn(linkObj)
+	}
+	return m.nLink.LinkSetPromiscOff(linkObj)
+}
+
+// setAllmulti turns all-multicast mode of the link on or off
+func (m *manager) setAllmulti(linkObj netlink.Link, on bool) error {
+	if on {
+		return m.nLink.LinkSetAllmulticastOn(linkObj)
+	}
+	return m.nLink.LinkSetAllmulticastOff(linkObj)
+}
+
+// saveEthtoolState saves the original values of the configured ethtool settings of the VF netdevice
+func (m *manager) saveEthtoolState(conf *types.PluginConf, ifName string) error {
+	ethConf := conf.Ethtool
+	if ethConf == nil {
+		return nil
+	}
 
-		// reset MTU
-		if conf.MTU != 0 {
-			if err = m.nLink.LinkSetMTU(linkObj, conf.OrigVfState.MTU); err != nil {
-				return fmt.Errorf("failed to set MTU on VF %s: %v", linkObj.Attrs().Name, err)
+	orig := &types.EthtoolConf{}
+	if len(ethConf.Features) > 0 {
+		features, err := m.ethtool.Features(ifName)
+		if err != nil {
+			return fmt.Errorf("failed to get features of VF %s: %v", ifName, err)
+		}
+		orig.Features = make(map[string]bool, len(ethConf.Features))
+		for name := range ethConf.Features {
+			value, ok := features[name]
+			if !ok {
+				return fmt.Errorf("feature %s is not supported by VF %s", name, ifName)
 			}
-			log.Info().Msgf("VF link %s MTU set to %d", linkObj.Attrs().Name, conf.OrigVfState.MTU)
+			orig.Features[name] = value
+		}
+	}
+
+	if ethConf.CombinedChannels != nil {
+		channels, err := m.ethtool.GetChannels(ifName)
+		if err != nil {
+			return fmt.Errorf("failed to get channels of VF %s: %v", ifName, err)
 		}
+		combined := int(channels.CombinedCount)
+		orig.CombinedChannels = &combined
+	}
 
-		// move VF device to init netns
-		if err = m.nLink.LinkSetNsFd(linkObj, int(initns.Fd())); err != nil {
-			return fmt.Errorf("failed to move interface %s to init netns: %v",
-				conf.OrigVfState.HostIFName, err)
+	if ethConf.Rings != nil {
+		ring, err := m.ethtool.GetRing(ifName)
+		if err != nil {
+			return fmt.Errorf("failed to get ring sizes of VF %s: %v", ifName, err)
 		}
+		orig.Rings = &types.EthtoolRings{}
+		if ethConf.Rings.RX != nil {
+			rx := int(ring.RxPending)
+			orig.Rings.RX = &rx
+		}
+		if ethConf.Rings.TX != nil {
+			tx := int(ring.TxPending)
+			orig.Rings.TX = &tx
+		}
+	}
+	conf.OrigVfState.Ethtool = orig
+	return nil
+}
 
+// setEthtoolConf applies ethtool settings to the VF netdevice, settings which are not set are not changed
+func (m *manager) setEthtoolConf(ifName string, ethConf *types.EthtoolConf) error {
+	if ethConf == nil {
 		return nil
-	})
+	}
+
+	if len(ethConf.Features) > 0 {
+		if err := m.ethtool.Change(ifName, ethConf.Features); err != nil {
+			return fmt.Errorf("failed to set features %v of VF %s: %v", ethConf.Features, ifName, err)
+		}
+		log.Info().Msgf("VF link %s features set to %v", ifName, ethConf.Features)
+	}
+
+	if ethConf.CombinedChannels != nil {
+		channels, err := m.ethtool.GetChannels(ifName)
+		if err != nil {
+			return fmt.Errorf("failed to get channels of VF %s: %v", ifName, err)
+		}
+		channels.CombinedCount = uint32(*ethConf.CombinedChannels)
+		if _, err = m.ethtool.SetChannels(ifName, channels); err != nil {
+			return fmt.Errorf("failed to set combined channels to %d for VF %s: %v",
+				*ethConf.CombinedChannels, ifName, err)
+		}
+		log.Info().Msgf("VF link %s combined channels set to %d", ifName, *ethConf.CombinedChannels)
+	}
+
+	if ethConf.Rings != nil {
+		ring, err := m.ethtool.GetRing(ifName)
+		if err != nil {
+			return fmt.Errorf("failed to get ring sizes of VF %s: %v", ifName, err)
+		}
+		if ethConf.Rings.RX != nil {
+			ring.RxPending = uint32(*ethConf.Rings.RX)
+		}
+		if ethConf.Rings.TX != nil {
+			ring.TxPending = uint32(*ethConf.Rings.TX)
+		}
+		if _, err = m.ethtool.SetRing(ifName, ring); err != nil {
+			return fmt.Errorf("failed to set ring sizes (rx %d, tx %d) for VF %s: %v",
+				ring.RxPending, ring.TxPending, ifName, err)
+		}
+		log.Info().Msgf("VF link %s ring sizes set to rx %d, tx %d", ifName, ring.RxPending, ring.TxPending)
+	}
+
+	return nil
+}
+
+// applySysctl sets interface-scoped sysctls for the VF netdevice, should be called in the network
+// namespace of the VF. Original values are not saved, because interface-scoped sysctls are reset
+// to the defaults of the network namespace when the VF is moved to another network namespace
+func (m *manager) applySysctl(conf *types.PluginConf, ifName string) error {
+	// dots in the interface name are replaced with slashes as dots are used as separators in sysctl keys
+	sysctlIfName := strings.ReplaceAll(ifName, ".", "/")
+	for key, value := range conf.Sysctl {
+		name := strings.ReplaceAll(key, types.IfNamePlaceholder, sysctlIfName)
+		if err := m.sysctl.Set(name, value); err != nil {
+			return fmt.Errorf("failed to set sysctl %s to %s: %v", name, value, err)
+		}
+		log.Info().Msgf("Sysctl %s set to %s", name, value)
+	}
+	return nil
+}
+
+// addVlanInterfaces creates VLAN sub-interfaces on top of the VF netdevice and brings them up,
+// should be called in the network namespace of the VF. Names of the created sub-interfaces
+// are saved in conf.ContVlanIfNames
+func (m *manager) addVlanInterfaces(conf *types.PluginConf, linkObj netlink.Link, podifName string) error {
+	for i := range conf.VlanInterfaces {
+		vlanIf := &conf.VlanInterfaces[i]
+		vlanLink := &netlink.Vlan{
+			LinkAttrs: netlink.LinkAttrs{
+				Name:        vlanIf.IfName(podifName),
+				ParentIndex: linkObj.Attrs().Index,
+			},
+			VlanId: vlanIf.ID,
+		}
+		if err := m.nLink.LinkAdd(vlanLink); err != nil {
+			return fmt.Errorf("failed to create VLAN %d sub-interface %s: %v", vlanIf.ID, vlanLink.Name, err)
+		}
+		conf.ContVlanIfNames = append(conf.ContVlanIfNames, vlanLink.Name)
+		if err := m.nLink.LinkSetUp(vlanLink); err != nil {
+			return fmt.Errorf("failed to set VLAN sub-interface %s up: %v", vlanLink.Name, err)
+		}
+		log.Info().Msgf("VLAN %d sub-interface %s created on %s", vlanIf.ID, vlanLink.Name, podifName)
+	}
+	return nil
+}
+
+// deleteVlanInterfaces deletes VLAN sub-interfaces created by addVlanInterfaces, should be called
+// in the network namespace of the VF. Sub-interfaces which no longer exist are skipped
+func (m *manager) deleteVlanInterfaces(conf *types.PluginConf) error {
+	for _, name := range conf.ContVlanIfNames {
+		vlanLink, err := m.nLink.LinkByName(name)
+		if err != nil {
+			log.Debug().Msgf("VLAN sub-interface %s not found, skip deletion: %v", name, err)
+			continue
+		}
+		if err = m.nLink.LinkDel(vlanLink); err != nil {
+			return fmt.Errorf("failed to delete VLAN sub-interface %s: %v", name, err)
+		}
+	}
+	conf.ContVlanIfNames = nil
+	return nil
+}
+
+// restoreVFAdminState brings the VF in the host network namespace up if it was up before cmdAdd
+func (m *manager) restoreVFAdminState(conf *types.PluginConf, linkObj netlink.Link) error {
+	if !conf.OrigVfState.NetdevSaved || !conf.OrigVfState.AdminUp {
+		return nil
+	}
+	if err := m.nLink.LinkSetUp(linkObj); err != nil {
+		return fmt.Errorf("failed to set link %s up: %q", conf.OrigVfState.HostIFName, err)
+	}
+	return nil
 }
 
 func getVfInfo(link netlink.Link, id int) *netlink.VfInfo {
@@ -225,20 +589,84 @@ func getVfInfo(link netlink.Link, id int) *netlink.VfInfo {
 	return nil
 }
 
-// ApplyVFConfig configure a VF with parameters given in PluginConf
-func (m *manager) ApplyVFConfig(conf *types.PluginConf) error {
+// ResolveRepresentor sets the name of the VF representor in conf
+func (m *manager) ResolveRepresentor(conf *types.PluginConf) error {
+	rep, err := m.sriov.GetVfRepresentor(conf.PFName, conf.VFID)
+	if err != nil {
+		return fmt.Errorf("failed to get VF's %d representor on NIC %s: %v", conf.VFID, conf.PFName, err)
+	}
+	conf.Representor = rep
+	return nil
+}
+
+// SaveState saves the original state of the representor and the VF which is restored when the VF is released,
+// should be called before the representor or the VF is changed
+func (m *manager) SaveState(conf *types.PluginConf) error {
+	rep, err := m.nLink.LinkByName(conf.Representor)
+	if err != nil {
+		return fmt.Errorf("failed to get representor link %s: %v", conf.Representor, err)
+	}
+	if err = m.saveRepState(conf, rep); err != nil {
+		return err
+	}
+	if err = m.saveVfState(conf); err != nil {
+		return err
+	}
+	// VF with userspace driver has no netdevice
+	if conf.IsUserspaceDriver {
+		return nil
+	}
+	return m.saveVfNetdevState(conf)
+}
+
+// saveVfState saves the VF configuration of the PF
+func (m *manager) saveVfState(conf *types.PluginConf) error {
 	pfLink, err := m.nLink.LinkByName(conf.PFName)
 	if err != nil {
 		return fmt.Errorf("failed to lookup master %q: %v", conf.PFName, err)
 	}
 
-	// Save current the VF state before modifying it
 	vfState := getVfInfo(pfLink, conf.VFID)
 	if vfState == nil {
 		return fmt.Errorf("failed to find vf %d for PF %s", conf.VFID, conf.PFName)
 	}
 
 	conf.OrigVfState.AdminMAC = vfState.Mac.String() // Save administrative MAC for restoring it later
+	conf.OrigVfState.SpoofChk = vfState.Spoofchk
+	conf.OrigVfState.Trust = vfState.Trust != 0
+	conf.OrigVfState.MinTxRate = int(vfState.MinTxRate)
+	conf.OrigVfState.MaxTxRate = int(vfState.MaxTxRate)
+	conf.OrigVfState.LinkState = vfState.LinkState
+	conf.OrigVfState.Saved = true
+	return nil
+}
+
+// saveVfNetdevState saves the state of the VF netdevice in the host network namespace
+func (m *manager) saveVfNetdevState(conf *types.PluginConf) error {
+	linkName := conf.OrigVfState.HostIFName
+	linkObj, err := m.nLink.LinkByName(linkName)
+	if err != nil {
+		return fmt.Errorf("error getting VF netdevice with name %s", linkName)
+	}
+
+	attrs := linkObj.Attrs()
+	conf.OrigVfState.EffectiveMAC = attrs.HardwareAddr.String()
+	conf.OrigVfState.MTU = attrs.MTU
+	conf.OrigVfState.AdminUp = attrs.Flags&net.FlagUp != 0
+	conf.OrigVfState.Promisc = attrs.Promisc == 1
+	conf.OrigVfState.Allmulti = attrs.Allmulti == 1
+	conf.OrigVfState.NetdevSaved = true
+
+	return m.saveEthtoolState(conf, linkName)
+}
+
+// ApplyVFConfig configure a VF with parameters given in PluginConf,
+// the original VF configuration should be saved by SaveState
+func (m *manager) ApplyVFConfig(conf *types.PluginConf) error {
+	pfLink, err := m.nLink.LinkByName(conf.PFName)
+	if err != nil {
+		return fmt.Errorf("failed to lookup master %q: %v", conf.PFName, err)
+	}
 
 	// Set mac address
 	if conf.MAC != "" {
@@ -253,6 +681,46 @@ func (m *manager) ApplyVFConfig(conf *types.PluginConf) error {
 		}
 	}
 
+	// Set spoof checking
+	if conf.SpoofChk != "" {
+		if err = m.nLink.LinkSetVfSpoofchk(pfLink, conf.VFID, conf.SpoofChk == "on"); err != nil {
+			return fmt.Errorf("failed to set spoofchk to %s for vf %d: %v", conf.SpoofChk, conf.VFID, err)
+		}
+	}
+
+	// Set trust mode
+	if conf.Trust != "" {
+		if err = m.nLink.LinkSetVfTrust(pfLink, conf.VFID, conf.Trust == "on"); err != nil {
+			return fmt.Errorf("failed to set trust to %s for vf %d: %v", conf.Trust, conf.VFID, err)
+		}
+	}
+
+	// Set transmit rate limits, rate which is not configured keeps its current value
+	if conf.MinTxRate != nil || conf.MaxTxRate != nil {
+		minTxRate, maxTxRate := conf.OrigVfState.MinTxRate, conf.OrigVfState.MaxTxRate
+		if conf.MinTxRate != nil {
+			minTxRate = *conf.MinTxRate
+		}
+		if conf.MaxTxRate != nil {
+			maxTxRate = *conf.MaxTxRate
+		}
+		if err = m.nLink.LinkSetVfRate(pfLink, conf.VFID, minTxRate, maxTxRate); err != nil {
+			return fmt.Errorf("failed to set tx rate (min %d, max %d) for vf %d: %v",
+				minTxRate, maxTxRate, conf.VFID, err)
+		}
+	}
+
+	// Set link state
+	if conf.LinkState != "" {
+		state, ok := vfLinkStates[conf.LinkState]
+		if !ok {
+			return fmt.Errorf("unknown link state %s", conf.LinkState)
+		}
+		if err = m.nLink.LinkSetVfState(pfLink, conf.VFID, state); err != nil {
+			return fmt.Errorf("failed to set link state to %s for vf %d: %v", conf.LinkState, conf.VFID, err)
+		}
+	}
+
 	return nil
 }
 
@@ -263,8 +731,47 @@ func (m *manager) ResetVFConfig(conf *types.PluginConf) error {
 		return fmt.Errorf("failed to lookup master %q: %v", conf.PFName, err)
 	}
 
+	// restore checks if the option should be restored: configured options are always restored,
+	// other options are restored if they were changed after the complete VF state was saved
+	vfState := getVfInfo(pfLink, conf.VFID)
+	restore := func(configured bool, changed func(vf *netlink.VfInfo) bool) bool {
+		return configured || conf.OrigVfState.Saved && (vfState == nil || changed(vfState))
+	}
+	orig := conf.OrigVfState
+
+	// Restore the original link state
+	if restore(conf.LinkState != "", func(vf *netlink.VfInfo) bool { return vf.LinkState != orig.LinkState }) {
+		if err = m.nLink.LinkSetVfState(pfLink, conf.VFID, conf.OrigVfState.LinkState); err != nil {
+			return fmt.Errorf("failed to restore original link state for vf %d: %v", conf.VFID, err)
+		}
+	}
+
+	// Restore the original transmit rate limits
+	if restore(conf.MinTxRate != nil || conf.MaxTxRate != nil, func(vf *netlink.VfInfo) bool {
+		return int(vf.MinTxRate) != orig.MinTxRate || int(vf.MaxTxRate) != orig.MaxTxRate
+	}) {
+		err = m.nLink.LinkSetVfRate(pfLink, conf.VFID, conf.OrigVfState.MinTxRate, conf.OrigVfState.MaxTxRate)
+		if err != nil {
+			return fmt.Errorf("failed to restore original tx rate for vf %d: %v", conf.VFID, err)
+		}
+	}
+
+	// Restore the original trust mode
+	if restore(conf.Trust != "", func(vf *netlink.VfInfo) bool { return (vf.Trust != 0) != orig.Trust }) {
+		if err = m.nLink.LinkSetVfTrust(pfLink, conf.VFID, conf.OrigVfState.Trust); err != nil {
+			return fmt.Errorf("failed to restore original trust for vf %d: %v", conf.VFID, err)
+		}
+	}
+
+	// Restore the original spoof checking
+	if restore(conf.SpoofChk != "", func(vf *netlink.VfInfo) bool { return vf.Spoofchk != orig.SpoofChk }) {
+		if err = m.nLink.LinkSetVfSpoofchk(pfLink, conf.VFID, conf.OrigVfState.SpoofChk); err != nil {
+			return fmt.Errorf("failed to restore original spoofchk for vf %d: %v", conf.VFID, err)
+		}
+	}
+
 	// Restore the original administrative MAC address
-	if conf.MAC != "" {
+	if restore(conf.MAC != "", func(vf *netlink.VfInfo) bool { return vf.Mac.String() != orig.AdminMAC }) {
 		var hwaddr net.HardwareAddr
 		hwaddr, err = net.ParseMAC(conf.OrigVfState.AdminMAC)
 		if err != nil {
@@ -279,24 +786,31 @@ func (m *manager) ResetVFConfig(conf *types.PluginConf) error {
 	return nil
 }
 
+// AttachRepresentor attaches the representor resolved by ResolveRepresentor to the bridge,
+// the original representor state should be saved by SaveState
 func (m *manager) AttachRepresentor(conf *types.PluginConf) error {
 	bridge, err := m.nLink.LinkByName(conf.ActualBridge)
 	if err != nil {
 		return fmt.Errorf("failed to get bridge link %s: %v", conf.ActualBridge, err)
 	}
 
-	conf.Representor, err = m.sriov.GetVfRepresentor(conf.PFName, conf.VFID)
-	if err != nil {
-		return fmt.Errorf("failed to get VF's %d representor on NIC %s: %v", conf.VFID, conf.PFName, err)
-	}
-
 	var rep netlink.Link
 	if rep, err = m.nLink.LinkByName(conf.Representor); err != nil {
 		return fmt.Errorf("failed to get representor link %s: %v", conf.Representor, err)
 	}
 
+	// representor is tagged before any other change, so that the attachment can be identified
+	// if the representor is left configured by a failed or interrupted command
+	defer func() {
+		if err != nil {
+			m.deleteRepTags(conf, rep)
+		}
+	}()
+	if err = m.setRepTags(conf, rep); err != nil {
+		return err
+	}
+
 	if conf.MTU != 0 {
-		conf.OrigRepState.MTU = rep.Attrs().MTU
 		if err = m.nLink.LinkSetMTU(rep, conf.MTU); err != nil {
 			return fmt.Errorf("failed to set MTU on representor %s: %v", conf.Representor, err)
 		}
@@ -319,6 +833,10 @@ func (m *manager) AttachRepresentor(conf *types.PluginConf) error {
 		}
 	}()
 
+	if err = m.configureRepBridgePort(conf, rep); err != nil {
+		return err
+	}
+
 	// if VF has any VLAN config we should remove default vlan on port
 	// if VLAN 1 explicitly requested we should not remove it from the port
 	if conf.Vlan > 1 || len(conf.Trunk) > 0 {
@@ -350,28 +868,332 @@ func (m *manager) AttachRepresentor(conf *types.PluginConf) error {
 	return nil
 }
 
-func (m *manager) addUplinkVlans(conf *types.PluginConf) error {
-	var uplink netlink.Link
-	var err error
+// setRepTags sets alias and altname which identify the attachment on the representor
+func (m *manager) setRepTags(conf *types.PluginConf, rep netlink.Link) error {
+	if conf.RepAlias != "" {
+		if err := m.nLink.LinkSetAlias(rep, conf.RepAlias); err != nil {
+			return fmt.Errorf("failed to set alias %q on representor %s: %v", conf.RepAlias, conf.Representor, err)
+		}
+	}
 
-	uplink, err = m.nLink.LinkByName(conf.PFName)
-	if err != nil {
-		return fmt.Errorf("failed to lookup PF %s: %v", conf.PFName, err)
+	if conf.RepAltName != "" {
+		if err := m.nLink.LinkAddAltName(rep, conf.RepAltName); err != nil {
+			return fmt.Errorf("failed to add altname %s to representor %s: %v", conf.RepAltName, conf.Representor, err)
+		}
 	}
+	return nil
+}
 
-	if bonduplink, bonderr := utils.GetParentBondForLink(m.nLink, uplink); bonderr == nil {
-		log.Debug().Msgf("Using bond master for adding uplink vlans: pf:%s - master:%s",
-			uplink.Attrs().Name, bonduplink.Attrs().Name)
-		uplink = bonduplink
+// deleteRepTags deletes altname and restores the original alias of the representor, errors are logged
+func (m *manager) deleteRepTags(conf *types.PluginConf, rep netlink.Link) {
+	if conf.RepAltName != "" {
+		if err := m.nLink.LinkDelAltName(rep, conf.RepAltName); err != nil {
+			log.Warn().Msgf("Failed to delete altname %s from representor %s: %v", conf.RepAltName, conf.Representor, err)
+		}
+	}
+	if conf.RepAlias != "" {
+		if err := m.nLink.LinkSetAlias(rep, conf.OrigRepState.Alias); err != nil {
+			log.Warn().Msgf("Failed to restore alias on representor %s: %v", conf.Representor, err)
+		}
 	}
+}
 
-	var vlans []int
-	if len(conf.Trunk) > 0 {
-		vlans = conf.Trunk
+// configureRepBridgePort applies bridge port attributes to the representor attached to the bridge,
+// the attributes are not restored on cleanup since the bridge port is destroyed when the representor is detached
+func (m *manager) configureRepBridgePort(conf *types.PluginConf, rep netlink.Link) error {
+	if conf.Isolated {
+		log.Info().Msgf("Isolating bridge port of rep %s", conf.Representor)
+		if err := m.nLink.LinkSetIsolated(rep, true); err != nil {
+			return fmt.Errorf("failed to isolate bridge port of representor %s: %v", conf.Representor, err)
+		}
 	}
 
-	if conf.Vlan > 0 {
-		vlans = append(vlans, conf.Vlan)
+	if conf.NeighSuppress {
+		log.Info().Msgf("Enabling ARP/ND suppression on bridge port of rep %s", conf.Representor)
+		if err := m.nLink.LinkSetBrNeighSuppress(rep, true); err != nil {
+			return fmt.Errorf("failed to enable neigh_suppress on representor %s: %v", conf.Representor, err)
+		}
+	}
+
+	if conf.PortFlags != nil {
+		log.Info().Msgf("Setting bridge port flags of rep %s", conf.Representor)
+		if err := m.setPortFlags(rep, conf.PortFlags); err != nil {
+			return fmt.Errorf("failed to set bridge port flags of representor %s: %v", conf.Representor, err)
+		}
+	}
+	return m.configureRepSTP(conf, rep)
+}
+
+// hasBridgePortOptions returns true if any option which changes bridge port attributes of the representor is set
+func hasBridgePortOptions(conf *types.PluginConf) bool {
+	return conf.Isolated || conf.NeighSuppress || conf.PortFlags != nil ||
+		conf.BpduGuard || conf.RootBlock || conf.Cost != nil || conf.Priority != nil
+}
+
+// configureRepSTP applies STP attributes to the representor bridge port
+func (m *manager) configureRepSTP(conf *types.PluginConf, rep netlink.Link) error {
+	if conf.BpduGuard {
+		log.Info().Msgf("Enabling BPDU guard on bridge port of rep %s", conf.Representor)
+		if err := m.nLink.LinkSetGuard(rep, true); err != nil {
+			return fmt.Errorf("failed to enable BPDU guard on representor %s: %v", conf.Representor, err)
+		}
+	}
+	if conf.RootBlock {
+		log.Info().Msgf("Enabling root block on bridge port of rep %s", conf.Representor)
+		if err := m.nLink.LinkSetRootBlock(rep, true); err != nil {
+			return fmt.Errorf("failed to enable root block on representor %s: %v", conf.Representor, err)
+		}
+	}
+	if conf.Cost != nil {
+		log.Info().Msgf("Setting STP cost %d on bridge port of rep %s", *conf.Cost, conf.Representor)
+		if err := m.nLink.LinkSetPortCost(rep, uint32(*conf.Cost)); err != nil {
+			return fmt.Errorf("failed to set STP cost on representor %s: %v", conf.Representor, err)
+		}
+	}
+	if conf.Priority != nil {
+		log.Info().Msgf("Setting STP priority %d on bridge port of rep %s", *conf.Priority, conf.Representor)
+		if err := m.nLink.LinkSetPortPriority(rep, uint16(*conf.Priority)); err != nil {
+			return fmt.Errorf("failed to set STP priority on representor %s: %v", conf.Representor, err)
+		}
+	}
+	return nil
+}
+
+// setPortFlags sets bridge port flags which are not nil, previous values are not saved
+// since the flags are reset when the representor is detached from the bridge
+func (m *manager) setPortFlags(rep netlink.Link, flags *types.PortFlags) error {
+	setters := []struct {
+		name  string
+		value *bool
+		set   func(netlink.Link, bool) error
+	}{
+		{"learning", flags.Learning, m.nLink.LinkSetLearning},
+		{"flood", flags.Flood, m.nLink.LinkSetFlood},
+		{"mcast_flood", flags.McastFlood, m.nLink.LinkSetMcastFlood},
+		{"bcast_flood", flags.BcastFlood, m.nLink.LinkSetBcastFlood},
+	}
+	for _, s := range setters {
+		if s.value == nil {
+			continue
+		}
+		if err := s.set(rep, *s.value); err != nil {
+			return fmt.Errorf("failed to set %s to %t: %v", s.name, *s.value, err)
+		}
+	}
+	return nil
+}
+
+// getPortFlags returns current values of the bridge port flags which are set in flags
+func getPortFlags(flags *types.PortFlags, portInfo *types.BridgePortInfo) *types.PortFlags {
+	actual := &types.PortFlags{}
+	if flags.Learning != nil {
+		actual.Learning = &portInfo.Learning
+	}
+	if flags.Flood != nil {
+		actual.Flood = &portInfo.Flood
+	}
+	if flags.McastFlood != nil {
+		actual.McastFlood = &portInfo.McastFlood
+	}
+	if flags.BcastFlood != nil {
+		actual.BcastFlood = &portInfo.BcastFlood
+	}
+	return actual
+}
+
+// AddRepresentorFDB adds static FDB entries for the VF MAC to the representor attached to the bridge,
+// entries are added for PVID and trunk VLANs, or without VLAN if the VF has no VLAN config.
+// Added entries are saved in conf.RepFdbEntries
+func (m *manager) AddRepresentorFDB(conf *types.PluginConf, mac string) error {
+	hwaddr, err := net.ParseMAC(mac)
+	if err != nil {
+		return fmt.Errorf("failed to parse MAC address %s: %v", mac, err)
+	}
+
+	rep, err := m.nLink.LinkByName(conf.Representor)
+	if err != nil {
+		return fmt.Errorf("failed to get representor %s link: %v", conf.Representor, err)
+	}
+
+	vlans := make([]int, 0, len(conf.Trunk)+1)
+	if conf.Vlan > 0 {
+		vlans = append(vlans, conf.Vlan)
+	}
+	vlans = append(vlans, conf.Trunk...)
+	if len(vlans) == 0 {
+		vlans = append(vlans, 0)
+	}
+
+	log.Info().Msgf("Adding static FDB entries for MAC %s on rep %s, VLANs %v", hwaddr, conf.Representor, vlans)
+	for _, vlan := range vlans {
+		if err = m.nLink.NeighSet(fdbNeigh(rep, hwaddr, vlan)); err != nil {
+			return fmt.Errorf("failed to add static FDB entry for MAC %s VLAN %d on representor %s: %v",
+				hwaddr, vlan, conf.Representor, err)
+		}
+		conf.RepFdbEntries = append(conf.RepFdbEntries, types.FdbEntry{MAC: hwaddr.String(), Vlan: vlan})
+	}
+	return nil
+}
+
+// deleteRepresentorFDB deletes static FDB entries added by AddRepresentorFDB, errors are logged
+// as FDB entries of the port are flushed anyway when the representor is detached from the bridge
+func (m *manager) deleteRepresentorFDB(conf *types.PluginConf, rep netlink.Link) {
+	for _, entry := range conf.RepFdbEntries {
+		hwaddr, err := net.ParseMAC(entry.MAC)
+		if err == nil {
+			err = m.nLink.NeighDel(fdbNeigh(rep, hwaddr, entry.Vlan))
+		}
+		if err != nil {
+			log.Warn().Msgf("Failed to delete static FDB entry for MAC %s VLAN %d on rep %s: %v",
+				entry.MAC, entry.Vlan, conf.Representor, err)
+		}
+	}
+	conf.RepFdbEntries = nil
+}
+
+// fdbNeigh returns static sticky FDB entry of the bridge for the port
+func fdbNeigh(port netlink.Link, hwaddr net.HardwareAddr, vlan int) *netlink.Neigh {
+	return &netlink.Neigh{
+		LinkIndex:    port.Attrs().Index,
+		Family:       unix.AF_BRIDGE,
+		Flags:        netlink.NTF_MASTER | netlink.NTF_STICKY,
+		State:        netlink.NUD_NOARP,
+		HardwareAddr: hwaddr,
+		Vlan:         vlan,
+	}
+}
+
+// AddBridgeNeighbors adds permanent neighbor entries for the VF addresses, the bridge uses them to answer
+// ARP and ND requests when neigh_suppress is enabled on the representor. Entries are added to the bridge device,
+// or to the bridge VLAN device of the VF PVID. Added entries are saved in conf.BridgeNeighbors
+func (m *manager) AddBridgeNeighbors(conf *types.PluginConf, mac string, ips []net.IP) error {
+	hwaddr, err := net.ParseMAC(mac)
+	if err != nil {
+		return fmt.Errorf("failed to parse MAC address %s: %v", mac, err)
+	}
+
+	dev, err := m.nLink.LinkByName(conf.ActualBridge)
+	if err != nil {
+		return fmt.Errorf("failed to get bridge link %s: %v", conf.ActualBridge, err)
+	}
+	if conf.Vlan > 0 {
+		if dev, err = m.getBridgeVlanDevice(dev, conf.Vlan); err != nil {
+			return err
+		}
+		if dev == nil {
+			// the bridge answers requests for the VLAN only from the neighbor table of its VLAN device
+			log.Warn().Msgf("VLAN %d device of the bridge %s not found, skip adding neighbor entries",
+				conf.Vlan, conf.ActualBridge)
+			return nil
+		}
+	}
+
+	for _, ip := range ips {
+		log.Info().Msgf("Adding neighbor entry %s lladdr %s on %s", ip, hwaddr, dev.Attrs().Name)
+		if err = m.nLink.NeighSet(bridgeNeigh(dev, ip, hwaddr)); err != nil {
+			return fmt.Errorf("failed to add neighbor entry %s on %s: %v", ip, dev.Attrs().Name, err)
+		}
+		conf.BridgeNeighbors = append(conf.BridgeNeighbors,
+			types.NeighEntry{Dev: dev.Attrs().Name, IP: ip.String(), MAC: hwaddr.String()})
+	}
+	return nil
+}
+
+// getBridgeVlanDevice returns VLAN device on top of the bridge for the VLAN, nil is returned if there is no such device
+func (m *manager) getBridgeVlanDevice(bridge netlink.Link, vlan int) (netlink.Link, error) {
+	links, err := m.nLink.LinkList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %v", err)
+	}
+	for _, link := range links {
+		if vlanLink, ok := link.(*netlink.Vlan); ok &&
+			vlanLink.ParentIndex == bridge.Attrs().Index && vlanLink.VlanId == vlan {
+			return link, nil
+		}
+	}
+	return nil, nil
+}
+
+// deleteBridgeNeighbors deletes neighbor entries added by AddBridgeNeighbors, errors are logged
+func (m *manager) deleteBridgeNeighbors(conf *types.PluginConf) {
+	for _, entry := range conf.BridgeNeighbors {
+		dev, err := m.nLink.LinkByName(entry.Dev)
+		if err == nil {
+			err = m.nLink.NeighDel(bridgeNeigh(dev, net.ParseIP(entry.IP), nil))
+		}
+		if err != nil {
+			log.Warn().Msgf("Failed to delete neighbor entry %s on %s: %v", entry.IP, entry.Dev, err)
+		}
+	}
+	conf.BridgeNeighbors = nil
+}
+
+// bridgeNeigh returns permanent neighbor entry of the device
+func bridgeNeigh(dev netlink.Link, ip net.IP, hwaddr net.HardwareAddr) *netlink.Neigh {
+	family := unix.AF_INET6
+	if ip.To4() != nil {
+		family = unix.AF_INET
+	}
+	return &netlink.Neigh{
+		LinkIndex:    dev.Attrs().Index,
+		Family:       family,
+		State:        netlink.NUD_PERMANENT,
+		IP:           ip,
+		HardwareAddr: hwaddr,
+	}
+}
+
+// saveRepState saves the original state of the representor before it is attached to the bridge
+func (m *manager) saveRepState(conf *types.PluginConf, rep netlink.Link) error {
+	attrs := rep.Attrs()
+	conf.OrigRepState = types.RepState{
+		MTU:     attrs.MTU,
+		AdminUp: attrs.Flags&net.FlagUp != 0,
+		Alias:   attrs.Alias,
+	}
+
+	if attrs.MasterIndex != 0 {
+		master, err := m.nLink.LinkByIndex(attrs.MasterIndex)
+		if err != nil {
+			return fmt.Errorf("failed to get master link of representor %s: %v", conf.Representor, err)
+		}
+		conf.OrigRepState.Master = master.Attrs().Name
+
+		if _, ok := master.(*netlink.Bridge); ok {
+			portFlags, err := m.nLink.LinkGetProtinfo(rep)
+			if err != nil {
+				return fmt.Errorf("failed to get bridge port flags of representor %s: %v", conf.Representor, err)
+			}
+			conf.OrigRepState.PortFlags = &portFlags
+		}
+	}
+
+	conf.OrigRepState.Saved = true
+	return nil
+}
+
+func (m *manager) addUplinkVlans(conf *types.PluginConf) error {
+	var uplink netlink.Link
+	var err error
+
+	uplink, err = m.nLink.LinkByName(conf.PFName)
+	if err != nil {
+		return fmt.Errorf("failed to lookup PF %s: %v", conf.PFName, err)
+	}
+
+	if bonduplink, bonderr := utils.GetParentBondForLink(m.nLink, uplink); bonderr == nil {
+		log.Debug().Msgf("Using bond master for adding uplink vlans: pf:%s - master:%s",
+			uplink.Attrs().Name, bonduplink.Attrs().Name)
+		uplink = bonduplink
+	}
+
+	var vlans []int
+	if len(conf.Trunk) > 0 {
+		vlans = conf.Trunk
+	}
+
+	if conf.Vlan > 0 {
+		vlans = append(vlans, conf.Vlan)
 	}
 
 	err = m.vlanUplinkLock.Lock()
@@ -400,20 +1222,45 @@ func (m *manager) DetachRepresentor(conf *types.PluginConf) error {
 		return fmt.Errorf("failed to set representor %s down: %v", conf.Representor, err)
 	}
 
-	// Restore MTU
-	if conf.MTU != 0 {
+	// Restore MTU, original MTU is unknown if the state was recovered
+	if conf.OrigRepState.MTU != 0 &&
+		(conf.MTU != 0 || conf.OrigRepState.Saved && rep.Attrs().MTU != conf.OrigRepState.MTU) {
 		if err = m.nLink.LinkSetMTU(rep, conf.OrigRepState.MTU); err != nil {
 			return fmt.Errorf("failed to set MTU on rep %s: %v", conf.Representor, err)
 		}
 		log.Info().Msgf("Restoring MTU %d on rep %s", conf.OrigRepState.MTU, conf.Representor)
 	}
 
+	m.deleteRepresentorFDB(conf, rep)
+	m.deleteBridgeNeighbors(conf)
+
 	log.Info().Msgf("Detaching rep %s from the bridge %s", conf.Representor, conf.ActualBridge)
 
+	// isolated flag and port flags are not restored,
+	// the bridge port is destroyed together with its attributes by LinkSetNoMaster
 	if err = m.nLink.LinkSetNoMaster(rep); err != nil {
 		return fmt.Errorf("failed to detatch representor %s from bridge: %v", conf.Representor, err)
 	}
 
+	if conf.RepAltName != "" {
+		if err = m.nLink.LinkDelAltName(rep, conf.RepAltName); err != nil {
+			log.Warn().Msgf("Failed to delete altname %s from representor %s: %v", conf.RepAltName, conf.Representor, err)
+		}
+	}
+
+	// Restore alias
+	if conf.RepAlias != "" || conf.OrigRepState.Saved && rep.Attrs().Alias != conf.OrigRepState.Alias {
+		if err = m.nLink.LinkSetAlias(rep, conf.OrigRepState.Alias); err != nil {
+			return fmt.Errorf("failed to restore alias on rep %s: %v", conf.Representor, err)
+		}
+	}
+
+	if conf.OrigRepState.Saved {
+		if err = m.restoreRepState(conf, rep); err != nil {
+			return err
+		}
+	}
+
 	if conf.SetUplinkVlan {
 		if err = m.deleteUplinkVlans(conf); err != nil {
 			log.Warn().Msgf("Failed to delete trunk VLANs from uplink %v", err)
@@ -423,6 +1270,45 @@ func (m *manager) DetachRepresentor(conf *types.PluginConf) error {
 	return nil
 }
 
+// restoreRepState restores the original master, bridge port flags and administrative state
+// of the representor detached from the bridge
+func (m *manager) restoreRepState(conf *types.PluginConf, rep netlink.Link) error {
+	orig := conf.OrigRepState
+	// representor attached to the bridge before cmdAdd is a leftover of a failed command,
+	// the saved state is not the original one
+	if orig.Master == conf.ActualBridge {
+		log.Warn().Msgf("representor %s was attached to the bridge %s before cmdAdd, skip restoring its state",
+			conf.Representor, conf.ActualBridge)
+		return nil
+	}
+
+	if orig.Master != "" {
+		master, err := m.nLink.LinkByName(orig.Master)
+		if err != nil {
+			return fmt.Errorf("failed to get original master %s of representor %s: %v",
+				orig.Master, conf.Representor, err)
+		}
+		log.Info().Msgf("Attaching rep %s back to %s", conf.Representor, orig.Master)
+		if err = m.nLink.LinkSetMaster(rep, master); err != nil {
+			return fmt.Errorf("failed to attach representor %s to %s: %v", conf.Representor, orig.Master, err)
+		}
+		if orig.PortFlags != nil {
+			if err = m.nLink.LinkSetProtinfo(rep, *orig.PortFlags); err != nil {
+				return fmt.Errorf("failed to restore bridge port flags of representor %s: %v",
+					conf.Representor, err)
+			}
+		}
+	}
+
+	if orig.AdminUp {
+		if err := m.nLink.LinkSetUp(rep); err != nil {
+			return fmt.Errorf("failed to set representor %s up: %v", conf.Representor, err)
+		}
+	}
+
+	return nil
+}
+
 func (m *manager) deleteUplinkVlans(conf *types.PluginConf) error {
 	var uplink netlink.Link
 	var err error
@@ -512,3 +1398,380 @@ func (m *manager) getUnusedVlanList(brif []netlink.Link, vlans []int) (unusedVla
 
 	return unusedVlans
 }
+
+// CheckRepresentor verifies that representor is still attached to the bridge and has expected configuration
+func (m *manager) CheckRepresentor(conf *types.PluginConf) error {
+	rep, err := m.nLink.LinkByName(conf.Representor)
+	if err != nil {
+		return linkLookupError(fmt.Sprintf("representor %s", conf.Representor), err)
+	}
+
+	bridge, err := m.nLink.LinkByName(conf.ActualBridge)
+	if err != nil {
+		return linkLookupError(fmt.Sprintf("bridge %s", conf.ActualBridge), err)
+	}
+
+	if rep.Attrs().MasterIndex != bridge.Attrs().Index {
+		return mismatchError("representor %s is not attached to the bridge %s", conf.Representor, conf.ActualBridge)
+	}
+
+	if rep.Attrs().Flags&net.FlagUp == 0 {
+		return mismatchError("representor %s is down", conf.Representor)
+	}
+
+	if conf.MTU != 0 && rep.Attrs().MTU != conf.MTU {
+		return mismatchError("representor %s MTU mismatch: expected %d, got %d",
+			conf.Representor, conf.MTU, rep.Attrs().MTU)
+	}
+
+	if err = m.checkRepBridgePort(conf, rep); err != nil {
+		return err
+	}
+
+	// representor without VLAN config uses default VLAN of the bridge, which is not managed by the plugin
+	if conf.Vlan == 0 && len(conf.Trunk) == 0 {
+		return nil
+	}
+
+	allbrif, err := utils.BridgeVlanList(m.nLink)
+	if err != nil {
+		return fmt.Errorf("failed to get bridge VLAN list: %v", err)
+	}
+
+	return checkRepresentorVlans(conf, allbrif[int32(rep.Attrs().Index)])
+}
+
+// GetHostInterfaces returns bridge and VF representor interfaces for the CNI result
+func (m *manager) GetHostInterfaces(conf *types.PluginConf) ([]*current.Interface, error) {
+	bridge, err := m.nLink.LinkByName(conf.ActualBridge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bridge link %s: %v", conf.ActualBridge, err)
+	}
+
+	rep, err := m.nLink.LinkByName(conf.Representor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get representor %s link: %v", conf.Representor, err)
+	}
+
+	return []*current.Interface{
+		{Name: bridge.Attrs().Name, Mac: bridge.Attrs().HardwareAddr.String()},
+		{Name: rep.Attrs().Name, Mac: rep.Attrs().HardwareAddr.String()},
+	}, nil
+}
+
+// checkRepBridgePort verifies that bridge port attributes of the representor have expected values
+func (m *manager) checkRepBridgePort(conf *types.PluginConf, rep netlink.Link) error {
+	if !hasBridgePortOptions(conf) {
+		return nil
+	}
+	portInfo, err := m.nLink.LinkGetBridgePortInfo(rep)
+	if err != nil {
+		return fmt.Errorf("failed to get bridge port attributes of representor %s: %v", conf.Representor, err)
+	}
+	if conf.Isolated && !portInfo.Isolated {
+		return mismatchError("representor %s bridge port is not isolated", conf.Representor)
+	}
+	if conf.NeighSuppress && !portInfo.NeighSuppress {
+		return mismatchError("representor %s bridge port has neigh_suppress disabled", conf.Representor)
+	}
+	if conf.PortFlags != nil {
+		if actual := getPortFlags(conf.PortFlags, &portInfo); !reflect.DeepEqual(actual, conf.PortFlags) {
+			return mismatchError("representor %s bridge port flags mismatch", conf.Representor)
+		}
+	}
+	return checkRepSTP(conf, &portInfo)
+}
+
+// checkRepSTP verifies that STP attributes of the representor bridge port have expected values
+func checkRepSTP(conf *types.PluginConf, portInfo *types.BridgePortInfo) error {
+	if conf.BpduGuard && !portInfo.Guard {
+		return mismatchError("representor %s bridge port has BPDU guard disabled", conf.Representor)
+	}
+	if conf.RootBlock && !portInfo.RootBlock {
+		return mismatchError("representor %s bridge port has root block disabled", conf.Representor)
+	}
+	if conf.Cost != nil && portInfo.Cost != uint32(*conf.Cost) {
+		return mismatchError("representor %s bridge port has STP cost %d, expected %d",
+			conf.Representor, portInfo.Cost, *conf.Cost)
+	}
+	if conf.Priority != nil && portInfo.Priority != uint16(*conf.Priority) {
+		return mismatchError("representor %s bridge port has STP priority %d, expected %d",
+			conf.Representor, portInfo.Priority, *conf.Priority)
+	}
+	return nil
+}
+
+// checkRepresentorVlans checks that representor has exactly PVID and trunk VLANs from the configuration
+func checkRepresentorVlans(conf *types.PluginConf, vlanInfo []*nl.BridgeVlanInfo) error {
+	expected := make(map[uint16]bool, len(conf.Trunk)+1)
+	for _, vlan := range conf.Trunk {
+		expected[uint16(vlan)] = false
+	}
+	if conf.Vlan > 0 {
+		expected[uint16(conf.Vlan)] = true
+	}
+
+	actual := make(map[uint16]*nl.BridgeVlanInfo, len(vlanInfo))
+	for _, info := range vlanInfo {
+		actual[info.Vid] = info
+	}
+
+	for vid, isPVID := range expected {
+		info, exist := actual[vid]
+		if !exist {
+			return mismatchError("representor %s VLAN mismatch: VLAN %d is not configured", conf.Representor, vid)
+		}
+		if info.PortVID() != isPVID || info.EngressUntag() != isPVID {
+			return mismatchError("representor %s VLAN mismatch: VLAN %d has unexpected flags %s",
+				conf.Representor, vid, info.String())
+		}
+	}
+
+	for vid := range actual {
+		if _, exist := expected[vid]; !exist {
+			return mismatchError("representor %s VLAN mismatch: unexpected VLAN %d is configured", conf.Representor, vid)
+		}
+	}
+
+	return nil
+}
+
+// CheckVF verifies that VF netdev exists in Pod netns and has expected configuration
+func (m *manager) CheckVF(conf *types.PluginConf, podifName string, result *current.Result, netns ns.NetNS) error {
+	contIfIndex := -1
+	for i, iface := range result.Interfaces {
+		if iface.Name == podifName && iface.Sandbox != "" {
+			contIfIndex = i
+			break
+		}
+	}
+	if contIfIndex < 0 {
+		return mismatchError("interface %s not found in prevResult", podifName)
+	}
+	contIface := result.Interfaces[contIfIndex]
+
+	return netns.Do(func(_ ns.NetNS) error {
+		linkObj, err := m.nLink.LinkByName(podifName)
+		if err != nil {
+			return linkLookupError(fmt.Sprintf("VF netdevice %s in container netns", podifName), err)
+		}
+
+		macAddress := linkObj.Attrs().HardwareAddr.String()
+		for _, expectedMAC := range []string{conf.MAC, contIface.Mac} {
+			if expectedMAC == "" {
+				continue
+			}
+			hwaddr, err := net.ParseMAC(expectedMAC)
+			if err != nil {
+				return fmt.Errorf("failed to parse MAC address %s: %v", expectedMAC, err)
+			}
+			if hwaddr.String() != macAddress {
+				return mismatchError("VF %s MAC address mismatch: expected %s, got %s", podifName, hwaddr, macAddress)
+			}
+		}
+
+		if conf.MTU != 0 && linkObj.Attrs().MTU != conf.MTU {
+			return mismatchError("VF %s MTU mismatch: expected %d, got %d", podifName, conf.MTU, linkObj.Attrs().MTU)
+		}
+
+		addrs, err := m.nLink.AddrList(linkObj, netlink.FAMILY_ALL)
+		if err != nil {
+			return fmt.Errorf("failed to list addresses for VF %s: %v", podifName, err)
+		}
+
+		for _, ipc := range result.IPs {
+			if ipc.Interface == nil || *ipc.Interface != contIfIndex {
+				continue
+			}
+			if !hasAddress(addrs, &ipc.Address) {
+				return mismatchError("VF %s IP address mismatch: %s is not configured", podifName, ipc.Address.String())
+			}
+		}
+
+		return m.checkVlanInterfaces(conf, podifName, result)
+	})
+}
+
+// checkVlanInterfaces verifies that VLAN sub-interfaces exist in the container netns
+// and have expected VLAN IDs and IP addresses, should be called in the network namespace of the VF
+func (m *manager) checkVlanInterfaces(conf *types.PluginConf, podifName string, result *current.Result) error {
+	for i := range conf.VlanInterfaces {
+		vlanIf := &conf.VlanInterfaces[i]
+		name := vlanIf.IfName(podifName)
+		linkObj, err := m.nLink.LinkByName(name)
+		if err != nil {
+			return linkLookupError(fmt.Sprintf("VLAN sub-interface %s in container netns", name), err)
+		}
+		vlanLink, ok := linkObj.(*netlink.Vlan)
+		if !ok || vlanLink.VlanId != vlanIf.ID {
+			return mismatchError("VLAN sub-interface %s mismatch: expected VLAN %d sub-interface", name, vlanIf.ID)
+		}
+
+		addrs, err := m.nLink.AddrList(linkObj, netlink.FAMILY_ALL)
+		if err != nil {
+			return fmt.Errorf("failed to list addresses for VLAN sub-interface %s: %v", name, err)
+		}
+		for _, ipc := range result.IPs {
+			if ipc.Interface == nil || *ipc.Interface < 0 || *ipc.Interface >= len(result.Interfaces) {
+				continue
+			}
+			iface := result.Interfaces[*ipc.Interface]
+			if iface.Name != name || iface.Sandbox == "" {
+				continue
+			}
+			if !hasAddress(addrs, &ipc.Address) {
+				return mismatchError("VLAN sub-interface %s IP address mismatch: %s is not configured",
+					name, ipc.Address.String())
+			}
+		}
+	}
+	return nil
+}
+
+// hasAddress checks if address with the same IP and prefix length exist in the addrs list
+func hasAddress(addrs []netlink.Addr, ipNet *net.IPNet) bool {
+	prefixLen, _ := ipNet.Mask.Size()
+	for _, addr := range addrs {
+		if addr.IPNet == nil || !addr.IP.Equal(ipNet.IP) {
+			continue
+		}
+		if ones, _ := addr.Mask.Size(); ones == prefixLen {
+			return true
+		}
+	}
+	return false
+}
+
+// mismatchError returns ErrStateMismatch with the details of the mismatch
+func mismatchError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrStateMismatch, fmt.Sprintf(format, args...))
+}
+
+// linkLookupError returns ErrStateMismatch if the link doesn't exist, otherwise the lookup error
+func linkLookupError(name string, err error) error {
+	if errors.As(err, &netlink.LinkNotFoundError{}) {
+		return mismatchError("%s not found", name)
+	}
+	return fmt.Errorf("failed to get %s: %v", name, err)
+}
+
+// CheckStatus checks that host is ready to attach VFs to the bridges,
+// all bridges should exist, have vlan_filtering enabled if VLANs are used
+// and have at least one uplink representor attached directly or through a bond
+func (m *manager) CheckStatus(conf *types.NetConf, bridges []string) error {
+	vlanFiltering := conf.Vlan != 0 || len(conf.Trunk) > 0
+	for _, brName := range bridges {
+		link, err := m.nLink.LinkByName(brName)
+		if err != nil {
+			return fmt.Errorf("failed to get bridge %s: %v", brName, err)
+		}
+		bridge, ok := link.(*netlink.Bridge)
+		if !ok {
+			return fmt.Errorf("link %s is not a bridge", brName)
+		}
+		if vlanFiltering && (bridge.VlanFiltering == nil || !*bridge.VlanFiltering) {
+			return fmt.Errorf("bridge %s has vlan_filtering disabled", brName)
+		}
+		hasUplink, err := m.hasUplinkRepresentor(bridge)
+		if err != nil {
+			return err
+		}
+		if !hasUplink {
+			return fmt.Errorf("no uplink representor is attached to bridge %s", brName)
+		}
+	}
+
+	if conf.DeviceID != "" {
+		uplinkName, err := m.sriov.GetUplinkRepresentor(conf.DeviceID)
+		if err != nil {
+			return fmt.Errorf("failed to get uplink representor for VF %s: %v", conf.DeviceID, err)
+		}
+		if _, err = m.nLink.LinkByName(uplinkName); err != nil {
+			return fmt.Errorf("failed to get uplink representor %s: %v", uplinkName, err)
+		}
+	}
+	return nil
+}
+
+// hasUplinkRepresentor returns true if bridge has uplink representor as a port
+// or as a member of a bond which is a bridge port
+func (m *manager) hasUplinkRepresentor(bridge netlink.Link) (bool, error) {
+	ports, err := utils.GetBridgeLinks(m.nLink, bridge)
+	if err != nil {
+		return false, fmt.Errorf("failed to get ports of bridge %s: %v", bridge.Attrs().Name, err)
+	}
+	for _, port := range ports {
+		candidates := []netlink.Link{port}
+		if port.Type() == "bond" {
+			candidates, err = utils.GetBridgeLinks(m.nLink, port)
+			if err != nil {
+				return false, fmt.Errorf("failed to get members of bond %s: %v", port.Attrs().Name, err)
+			}
+		}
+		for _, link := range candidates {
+			flavour, err := m.sriov.GetRepresentorPortFlavour(link.Attrs().Name)
+			if err != nil {
+				// not a representor
+				continue
+			}
+			if flavour == sriovnet.PORT_FLAVOUR_PHYSICAL {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// RecoverState reconstructs the original state of the VF and the representor
+// when cached state is not available. Values which can't be recovered
+// (VF name, effective MAC, MTU) are left unset and are not restored on release,
+// netns can be nil if container network namespace doesn't exist.
+func (m *manager) RecoverState(conf *types.PluginConf, podifName string, netns ns.NetNS) error {
+	if err := m.ResolveRepresentor(conf); err != nil {
+		return err
+	}
+	rep, err := m.nLink.LinkByName(conf.Representor)
+	if err != nil {
+		return fmt.Errorf("failed to get representor link %s: %v", conf.Representor, err)
+	}
+	// representor which is not attached to the bridge of the network was already released
+	// or the VF was reused by an attachment of another network, its state must not be touched
+	bridge, err := m.nLink.LinkByName(conf.ActualBridge)
+	if err != nil {
+		return fmt.Errorf("failed to get bridge link %s: %v", conf.ActualBridge, err)
+	}
+	if rep.Attrs().MasterIndex != bridge.Attrs().Index {
+		return fmt.Errorf("representor %s is not attached to the bridge %s", conf.Representor, conf.ActualBridge)
+	}
+
+	if conf.MAC != "" {
+		conf.OrigVfState.AdminMAC = defaultVfAdminMAC
+	}
+	if conf.SpoofChk != "" {
+		conf.OrigVfState.SpoofChk = defaultVfSpoofChk
+	}
+	if conf.Trust != "" {
+		conf.OrigVfState.Trust = defaultVfTrust
+	}
+	if conf.MinTxRate != nil || conf.MaxTxRate != nil {
+		conf.OrigVfState.MinTxRate = defaultVfTxRate
+		conf.OrigVfState.MaxTxRate = defaultVfTxRate
+	}
+	if conf.LinkState != "" {
+		conf.OrigVfState.LinkState = defaultVfLinkState
+	}
+
+	if conf.IsUserspaceDriver || conf.OrigVfState.HostIFName != "" || netns == nil {
+		// VF is not in the container network namespace
+		return nil
+	}
+
+	return netns.Do(func(_ ns.NetNS) error {
+		if _, err := m.nLink.LinkByName(podifName); err != nil {
+			log.Warn().Msgf("VF %s not found in container namespace: %v", podifName, err)
+			return nil
+		}
+		conf.ContIFNames = podifName
+		return nil
+	})
+}
@@ -4,14 +4,20 @@ import (
 	"errors"
 	"net"
 	"os"
+	"time"
 
+	"github.com/Mellanox/sriovnet"
+	current "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/containernetworking/plugins/pkg/ns"
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 	"github.com/rs/zerolog"
+	"github.com/safchain/ethtool"
 	"github.com/stretchr/testify/mock"
 	"github.com/vishvananda/netlink"
 	nl "github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
 
 	mgrMocks "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/manager/mocks"
 	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
@@ -54,6 +60,14 @@ func newFakeNs() ns.NetNS {
 	}
 }
 
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
 // FakeLink is a dummy netlink struct used during testing
 type FakeLink struct {
 	netlink.LinkAttrs
@@ -118,6 +132,15 @@ var _ = Describe("IPCLock", func() {
 			err1 := lock.Lock()
 			Expect(err1).To(HaveOccurred())
 		})
+		It("Lock with timeout file locked by another lock (failed)", func() {
+			lock1 := NewIPCLock(testpath + "flock.lock")
+			lock2 := NewIPCLock(testpath + "flock.lock")
+			Expect(lock1.LockWithTimeout(time.Second)).NotTo(HaveOccurred())
+			Expect(lock2.LockWithTimeout(200 * time.Millisecond)).To(MatchError(ErrLockTimeout))
+			Expect(lock1.Unlock()).NotTo(HaveOccurred())
+			Expect(lock2.LockWithTimeout(time.Second)).NotTo(HaveOccurred())
+			Expect(lock2.Unlock()).NotTo(HaveOccurred())
+		})
 		It("Unlock non-existing file with existing path (should be a no-op) (success)", func() {
 			lock := NewIPCLock(testpath + "flock.lock")
 			err1 := lock.Unlock()
@@ -234,7 +257,6 @@ var _ = Describe("Manager", func() {
 			m := manager{nLink: mocked}
 			_, err := m.SetupVF(netconf, podifName, contID, targetNetNS)
 			Expect(err).NotTo(HaveOccurred())
-			Expect(netconf.OrigVfState.MTU).To(Equal(origMTU))
 			mocked.AssertExpectations(t)
 		})
 	})
@@ -325,6 +347,93 @@ var _ = Describe("Manager", func() {
 			mocked.AssertExpectations(t)
 		})
 	})
+	Context("Checking ReleaseVF function - recovered state", func() {
+		var (
+			netconf  *types.PluginConf
+			mocked   *utilsMocks.Netlink
+			fakeLink *FakeLink
+		)
+
+		BeforeEach(func() {
+			// original name, effective MAC and MTU of the VF are unknown
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID: "0000:af:06.0",
+				},
+				PFName:      "enp175s0f1",
+				VFID:        0,
+				MAC:         "aa:f3:8d:65:1b:d4",
+				MTU:         1600,
+				ContIFNames: "net1",
+			}
+			fakeLink = &FakeLink{netlink.LinkAttrs{Index: 1000, Name: "net1"}}
+			mocked = &utilsMocks.Netlink{}
+			mocked.On("LinkByName", netconf.ContIFNames).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+		})
+		It("Keeps VF name and doesn't restore effective MAC and MTU", func() {
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil).Once()
+			m := manager{nLink: mocked}
+			Expect(m.ReleaseVF(netconf, "net1", "dummycid", newFakeNs())).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+		It("VF name is used in init netns, should rename VF to temporary name", func() {
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(unix.EEXIST).Once()
+			mocked.On("LinkSetName", fakeLink, "temp_1000").Return(nil).Once()
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil).Once()
+			m := manager{nLink: mocked}
+			Expect(m.ReleaseVF(netconf, "net1", "dummycid", newFakeNs())).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+		It("Fails to move VF to init netns", func() {
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(errors.New("some error")).Once()
+			m := manager{nLink: mocked}
+			Expect(m.ReleaseVF(netconf, "net1", "dummycid", newFakeNs())).To(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking RestoreHostVF function", func() {
+		var netconf *types.PluginConf
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID: "0000:af:06.0",
+				},
+				PFName:      "enp175s0f1",
+				VFID:        0,
+				MAC:         "aa:f3:8d:65:1b:d4",
+				MTU:         1600,
+				ContIFNames: "net1",
+				OrigVfState: types.VfState{
+					HostIFName:   "origname",
+					EffectiveMAC: "c6:c8:7f:1f:21:90",
+					MTU:          1500,
+				},
+			}
+		})
+		It("Restores VF name, effective MAC address and MTU in host namespace", func() {
+			mocked := &utilsMocks.Netlink{}
+			// VF returned by the kernel, name is taken from the tmp sysfs
+			fakeLink := &FakeLink{netlink.LinkAttrs{Index: 1000, Name: "enp175s6"}}
+
+			mocked.On("LinkByName", "enp175s6").Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, netconf.OrigVfState.HostIFName).Return(nil)
+			mocked.On("LinkSetMTU", fakeLink, netconf.OrigVfState.MTU).Return(nil)
+			origEffMac, err := net.ParseMAC(netconf.OrigVfState.EffectiveMAC)
+			Expect(err).NotTo(HaveOccurred())
+			mocked.On("LinkSetHardwareAddr", fakeLink, origEffMac).Return(nil)
+			m := manager{nLink: mocked}
+			Expect(m.RestoreHostVF(netconf)).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+		It("VF netdev not found in host namespace (failure)", func() {
+			netconf.DeviceID = "0000:af:07.0"
+			m := manager{nLink: &utilsMocks.Netlink{}}
+			Expect(m.RestoreHostVF(netconf)).To(HaveOccurred())
+		})
+	})
 	Context("Checking ResetVFConfig function - restore config no user params", func() {
 		var (
 			netconf *types.PluginConf
@@ -392,6 +501,85 @@ var _ = Describe("Manager", func() {
 			mocked.AssertExpectations(t)
 		})
 	})
+	Context("Checking ResolveRepresentor and SaveState functions", func() {
+		var (
+			netconf  *types.PluginConf
+			mockedNl *utilsMocks.Netlink
+			mockedSr *utilsMocks.Sriovnet
+			fakeRep  *FakeLink
+			fakePF   *FakeLink
+			fakeVF   *FakeLink
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID: "0000:af:06.0",
+				},
+				Representor: "dummylink",
+				PFName:      "enp175s0f1",
+				VFID:        3,
+				OrigVfState: types.VfState{
+					HostIFName: "enp175s6",
+				},
+			}
+			fakeRep = &FakeLink{netlink.LinkAttrs{Name: netconf.Representor, MTU: 1500, Alias: "old alias"}}
+			fakePF = &FakeLink{netlink.LinkAttrs{Name: netconf.PFName, Vfs: []netlink.VfInfo{
+				{ID: 3, Spoofchk: true, LinkState: netlink.VF_LINK_STATE_AUTO}}}}
+			fakeVF = &FakeLink{netlink.LinkAttrs{Name: "enp175s6", MTU: 9000, Flags: net.FlagUp}}
+			mockedNl = &utilsMocks.Netlink{}
+			mockedSr = &utilsMocks.Sriovnet{}
+		})
+		It("Resolves representor", func() {
+			netconf.Representor = ""
+			mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return("pf0vf3", nil)
+			m := manager{sriov: mockedSr}
+			Expect(m.ResolveRepresentor(netconf)).NotTo(HaveOccurred())
+			Expect(netconf.Representor).To(Equal("pf0vf3"))
+			mockedSr.AssertExpectations(t)
+		})
+		It("Fails to resolve representor", func() {
+			mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return("", errors.New("not found"))
+			m := manager{sriov: mockedSr}
+			Expect(m.ResolveRepresentor(netconf)).To(HaveOccurred())
+		})
+		It("Saves representor, VF and VF netdevice state", func() {
+			mockedNl.On("LinkByName", netconf.Representor).Return(fakeRep, nil)
+			mockedNl.On("LinkByName", netconf.PFName).Return(fakePF, nil)
+			mockedNl.On("LinkByName", netconf.OrigVfState.HostIFName).Return(fakeVF, nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.SaveState(netconf)).NotTo(HaveOccurred())
+			Expect(netconf.OrigRepState).To(Equal(types.RepState{MTU: 1500, Alias: "old alias", Saved: true}))
+			Expect(netconf.OrigVfState).To(Equal(types.VfState{
+				HostIFName:  "enp175s6",
+				SpoofChk:    true,
+				LinkState:   netlink.VF_LINK_STATE_AUTO,
+				Saved:       true,
+				MTU:         9000,
+				AdminUp:     true,
+				NetdevSaved: true,
+			}))
+			mockedNl.AssertExpectations(t)
+		})
+		It("VF with userspace driver, does not save VF netdevice state", func() {
+			netconf.IsUserspaceDriver = true
+			netconf.OrigVfState.HostIFName = ""
+			mockedNl.On("LinkByName", netconf.Representor).Return(fakeRep, nil)
+			mockedNl.On("LinkByName", netconf.PFName).Return(fakePF, nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.SaveState(netconf)).NotTo(HaveOccurred())
+			Expect(netconf.OrigVfState.Saved).To(BeTrue())
+			Expect(netconf.OrigVfState.NetdevSaved).To(BeFalse())
+			mockedNl.AssertExpectations(t)
+		})
+		It("Fails to find VF of the PF", func() {
+			netconf.VFID = 4
+			mockedNl.On("LinkByName", netconf.Representor).Return(fakeRep, nil)
+			mockedNl.On("LinkByName", netconf.PFName).Return(fakePF, nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.SaveState(netconf)).To(HaveOccurred())
+		})
+	})
 	Context("Checking AttachRepresentor function", func() {
 		var (
 			netconf *types.PluginConf
@@ -417,7 +605,6 @@ var _ = Describe("Manager", func() {
 			newMtu := 2000
 			netconf.MTU = newMtu
 			mockedNl := &utilsMocks.Netlink{}
-			mockedSr := &utilsMocks.Sriovnet{}
 			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "cni0"}}
 			fakeLink := &FakeLink{netlink.LinkAttrs{
 				Name:        netconf.Representor,
@@ -427,7 +614,6 @@ var _ = Describe("Manager", func() {
 
 			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
 			mockedNl.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
-			mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return(fakeLink.Name, nil)
 			mockedNl.On("LinkSetUp", fakeLink).Return(nil)
 			mockedNl.On("LinkSetMaster", fakeLink, fakeBridge).Run(func(args mock.Arguments) {
 				link := args.Get(0).(netlink.Link)
@@ -440,17 +626,14 @@ var _ = Describe("Manager", func() {
 			mockedNl.On("BridgeVlanAdd", fakeLink, uint16(6), false, false, false, true).Return(nil)
 			mockedNl.On("LinkSetMTU", fakeLink, newMtu).Return(nil)
 
-			m := manager{nLink: mockedNl, sriov: mockedSr}
+			m := manager{nLink: mockedNl}
 			err := m.AttachRepresentor(netconf)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(fakeLink.Attrs().MasterIndex).To(Equal(fakeBridge.Attrs().Index))
 			mockedNl.AssertExpectations(t)
-			mockedSr.AssertExpectations(t)
-			Expect(netconf.OrigRepState.MTU).To(Equal(origMtu))
 		})
 		It("Attaching dummy link to the bridge (failure)", func() {
 			mockedNl := &utilsMocks.Netlink{}
-			mockedSr := &utilsMocks.Sriovnet{}
 			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: "cni0"}}
 			fakeLink := &FakeLink{netlink.LinkAttrs{
 				Name:        netconf.Representor,
@@ -459,15 +642,13 @@ var _ = Describe("Manager", func() {
 
 			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
 			mockedNl.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
-			mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return(fakeLink.Name, nil)
 			mockedNl.On("LinkSetUp", fakeLink).Return(nil)
 			mockedNl.On("LinkSetMaster", fakeLink, fakeBridge).Return(errors.New("some error"))
 
-			m := manager{nLink: mockedNl, sriov: mockedSr}
+			m := manager{nLink: mockedNl}
 			err := m.AttachRepresentor(netconf)
 			Expect(err).To(HaveOccurred())
 			mockedNl.AssertExpectations(t)
-			mockedSr.AssertExpectations(t)
 		})
 		It("Attaching dummy link to the bridge setting uplink vlans (success)", func() {
 			origMtu := 1500
@@ -475,7 +656,6 @@ var _ = Describe("Manager", func() {
 			netconf.MTU = newMtu
 			netconf.SetUplinkVlan = true
 			mockedNl := &utilsMocks.Netlink{}
-			mockedSr := &utilsMocks.Sriovnet{}
 			mockedLock := &mgrMocks.IPCLock{}
 			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "cni0"}}
 			fakeLink := &FakeLink{netlink.LinkAttrs{
@@ -491,7 +671,6 @@ var _ = Describe("Manager", func() {
 
 			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
 			mockedNl.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
-			mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return(fakeLink.Name, nil)
 			mockedNl.On("LinkSetUp", fakeLink).Return(nil)
 			mockedNl.On("LinkSetMaster", fakeLink, fakeBridge).Run(func(args mock.Arguments) {
 				link := args.Get(0).(netlink.Link)
@@ -514,12 +693,11 @@ var _ = Describe("Manager", func() {
 			mockedNl.On("BridgeVlanAdd", fakeUpLink, uint16(6), false, false, false, true).Return(nil)
 			mockedLock.On("Unlock").Return(nil)
 
-			m := manager{nLink: mockedNl, sriov: mockedSr, vlanUplinkLock: mockedLock}
+			m := manager{nLink: mockedNl, vlanUplinkLock: mockedLock}
 			err := m.AttachRepresentor(netconf)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(fakeUpLink.Attrs().MasterIndex).To(Equal(fakeBridge.Attrs().Index))
 			mockedNl.AssertExpectations(t)
-			mockedSr.AssertExpectations(t)
 		})
 		It("Attaching dummy link to the bridge setting bond uplink vlans (success)", func() {
 			origMtu := 1500
@@ -527,7 +705,6 @@ var _ = Describe("Manager", func() {
 			netconf.MTU = newMtu
 			netconf.SetUplinkVlan = true
 			mockedNl := &utilsMocks.Netlink{}
-			mockedSr := &utilsMocks.Sriovnet{}
 			mockedLock := &mgrMocks.IPCLock{}
 			fakeBridge := &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "cni0"}}
 			fakeLink := &FakeLink{netlink.LinkAttrs{
@@ -550,7 +727,6 @@ var _ = Describe("Manager", func() {
 
 			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
 			mockedNl.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
-			mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return(fakeLink.Name, nil)
 			mockedNl.On("LinkSetUp", fakeLink).Return(nil)
 			mockedNl.On("LinkSetMaster", fakeLink, fakeBridge).Run(func(args mock.Arguments) {
 				link := args.Get(0).(netlink.Link)
@@ -573,12 +749,11 @@ var _ = Describe("Manager", func() {
 			mockedNl.On("BridgeVlanAdd", fakeBondUpLink, uint16(6), false, false, false, true).Return(nil)
 			mockedLock.On("Unlock").Return(nil)
 
-			m := manager{nLink: mockedNl, sriov: mockedSr, vlanUplinkLock: mockedLock}
+			m := manager{nLink: mockedNl, vlanUplinkLock: mockedLock}
 			err := m.AttachRepresentor(netconf)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(fakeBondUpLink.Attrs().MasterIndex).To(Equal(fakeBridge.Attrs().Index))
 			mockedNl.AssertExpectations(t)
-			mockedSr.AssertExpectations(t)
 		})
 	})
 	Context("Checking DetachRepresentor function", func() {
@@ -627,6 +802,17 @@ var _ = Describe("Manager", func() {
 			Expect(fakeLink.Attrs().MasterIndex).To(Equal(0))
 			mocked.AssertExpectations(t)
 		})
+		It("Original MTU is unknown, should not restore MTU", func() {
+			netconf.OrigRepState.MTU = 0
+			mocked := &utilsMocks.Netlink{}
+			fakeLink := &FakeLink{netlink.LinkAttrs{Name: netconf.Representor, MTU: newMtu, MasterIndex: 1000}}
+			mocked.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetNoMaster", fakeLink).Return(nil)
+			m := manager{nLink: mocked}
+			Expect(m.DetachRepresentor(netconf)).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
 		It("Detaching dummy link from the bridge and removing uplink vlans (success)", func() {
 			netconf.SetUplinkVlan = true
 			mocked := &utilsMocks.Netlink{}
@@ -864,7 +1050,7 @@ var _ = Describe("Manager", func() {
 				OrigVfState: types.VfState{},
 			}
 		})
-		It("Restores original VF configurations", func() {
+		It("Saves original VF admin MAC and sets the new one", func() {
 			mocked := &utilsMocks.Netlink{}
 			fakeLink := &FakeLink{netlink.LinkAttrs{Vfs: []netlink.VfInfo{{ID: 3, Mac: origMac}}}}
 
@@ -873,10 +1059,1410 @@ var _ = Describe("Manager", func() {
 			mocked.On("LinkSetVfHardwareAddr", fakeLink, netconf.VFID, newMac).Return(nil)
 
 			m := manager{nLink: mocked}
+			Expect(m.saveVfState(netconf)).NotTo(HaveOccurred())
 			err := m.ApplyVFConfig(netconf)
 			Expect(err).NotTo(HaveOccurred())
 			mocked.AssertExpectations(t)
 			Expect(netconf.OrigVfState.AdminMAC).To(Equal(origMac.String()))
 		})
 	})
+	Context("Checking ApplyVFConfig and ResetVFConfig functions - VF options", func() {
+		// vfOption describes a VF option: configure sets the option in the network configuration,
+		// orig sets the original value of the option in the VF info reported by the PF, setter is the Netlink
+		// method which changes the option, applied and restored are the setter arguments following the PF link
+		// and VF ID
+		type vfOption struct {
+			configure func(conf *types.NetConf)
+			orig      func(vf *netlink.VfInfo)
+			setter    string
+			applied   []interface{}
+			restored  []interface{}
+		}
+		var (
+			netconf  *types.PluginConf
+			mocked   *utilsMocks.Netlink
+			fakeLink *FakeLink
+		)
+
+		setup := func(opts ...vfOption) {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID: "0000:af:06.0",
+				},
+				PFName: "enp175s0f1",
+				VFID:   3,
+			}
+			vf := netlink.VfInfo{ID: netconf.VFID}
+			for _, opt := range opts {
+				opt.configure(&netconf.NetConf)
+				opt.orig(&vf)
+			}
+			fakeLink = &FakeLink{netlink.LinkAttrs{Vfs: []netlink.VfInfo{vf}}}
+			mocked = &utilsMocks.Netlink{}
+			mocked.On("LinkByName", netconf.PFName).Return(fakeLink, nil)
+		}
+		setterArgs := func(args []interface{}) []interface{} {
+			return append([]interface{}{fakeLink, netconf.VFID}, args...)
+		}
+
+		linkState := vfOption{
+			configure: func(conf *types.NetConf) { conf.LinkState = "disable" },
+			orig:      func(vf *netlink.VfInfo) { vf.LinkState = netlink.VF_LINK_STATE_ENABLE },
+			setter:    "LinkSetVfState",
+			applied:   []interface{}{netlink.VF_LINK_STATE_DISABLE},
+			restored:  []interface{}{netlink.VF_LINK_STATE_ENABLE},
+		}
+		txRate := vfOption{
+			configure: func(conf *types.NetConf) { maxTxRate := 1000; conf.MaxTxRate = &maxTxRate },
+			orig:      func(vf *netlink.VfInfo) { vf.MinTxRate, vf.MaxTxRate = 10, 500 },
+			setter:    "LinkSetVfRate",
+			applied:   []interface{}{10, 1000},
+			restored:  []interface{}{10, 500},
+		}
+		trust := vfOption{
+			configure: func(conf *types.NetConf) { conf.Trust = "on" },
+			orig:      func(vf *netlink.VfInfo) { vf.Trust = 0 },
+			setter:    "LinkSetVfTrust",
+			applied:   []interface{}{true},
+			restored:  []interface{}{false},
+		}
+		spoofChk := vfOption{
+			configure: func(conf *types.NetConf) { conf.SpoofChk = "off" },
+			orig:      func(vf *netlink.VfInfo) { vf.Spoofchk = true },
+			setter:    "LinkSetVfSpoofchk",
+			applied:   []interface{}{false},
+			restored:  []interface{}{true},
+		}
+
+		DescribeTable("Applies the option and restores the saved original value",
+			func(opt vfOption) {
+				setup(opt)
+				mocked.On(opt.setter, setterArgs(opt.applied)...).Return(nil).Once()
+				mocked.On(opt.setter, setterArgs(opt.restored)...).Return(nil).Once()
+				m := manager{nLink: mocked}
+				Expect(m.saveVfState(netconf)).NotTo(HaveOccurred())
+				Expect(m.ApplyVFConfig(netconf)).NotTo(HaveOccurred())
+				Expect(m.ResetVFConfig(netconf)).NotTo(HaveOccurred())
+				mocked.AssertExpectations(t)
+			},
+			Entry("link state", linkState),
+			Entry("max tx rate, keeps current min tx rate", txRate),
+			Entry("trust", trust),
+			Entry("spoofchk", spoofChk),
+		)
+		DescribeTable("Fails to apply the option",
+			func(opt vfOption) {
+				setup(opt)
+				mocked.On(opt.setter, setterArgs(opt.applied)...).Return(errors.New("some error")).Once()
+				m := manager{nLink: mocked}
+				Expect(m.saveVfState(netconf)).NotTo(HaveOccurred())
+				Expect(m.ApplyVFConfig(netconf)).To(HaveOccurred())
+			},
+			Entry("link state", linkState),
+			Entry("tx rate", txRate),
+			Entry("trust", trust),
+			Entry("spoofchk", spoofChk),
+		)
+		DescribeTable("Fails to restore the option",
+			func(opt vfOption) {
+				setup(opt)
+				mocked.On(opt.setter, setterArgs(opt.restored)...).Return(errors.New("some error")).Once()
+				m := manager{nLink: mocked}
+				Expect(m.saveVfState(netconf)).NotTo(HaveOccurred())
+				Expect(m.ResetVFConfig(netconf)).To(HaveOccurred())
+			},
+			Entry("link state", linkState),
+			Entry("tx rate", txRate),
+			Entry("trust", trust),
+			Entry("spoofchk", spoofChk),
+		)
+		// ResetVFConfig is called by CmdAdd if ApplyVFConfig or a later step fails
+		DescribeTable("Restores options applied before ApplyVFConfig failed",
+			func(applied vfOption, failed vfOption) {
+				setup(applied, failed)
+				mocked.On(applied.setter, setterArgs(applied.applied)...).Return(nil).Once()
+				mocked.On(failed.setter, setterArgs(failed.applied)...).Return(errors.New("some error")).Once()
+				mocked.On(applied.setter, setterArgs(applied.restored)...).Return(nil).Once()
+				mocked.On(failed.setter, setterArgs(failed.restored)...).Return(nil).Once()
+				m := manager{nLink: mocked}
+				Expect(m.saveVfState(netconf)).NotTo(HaveOccurred())
+				Expect(m.ApplyVFConfig(netconf)).To(HaveOccurred())
+				Expect(m.ResetVFConfig(netconf)).NotTo(HaveOccurred())
+				mocked.AssertExpectations(t)
+			},
+			Entry("spoofchk, trust fails", spoofChk, trust),
+			Entry("trust, tx rate fails", trust, txRate),
+			Entry("tx rate, link state fails", txRate, linkState),
+			Entry("spoofchk, failed link state is reset too", spoofChk, linkState),
+		)
+	})
+	Context("Checking SetupVF and ReleaseVF functions - ethtool", func() {
+		var (
+			netconf      *types.PluginConf
+			mocked       *utilsMocks.Netlink
+			mockedEth    *utilsMocks.Ethtool
+			channels     int
+			ringSize     int
+			origChannels int
+			origRingRX   int
+		)
+
+		BeforeEach(func() {
+			channels = 4
+			ringSize = 4096
+			origChannels = 8
+			origRingRX = 1024
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID: "0000:af:06.0",
+					Ethtool: &types.EthtoolConf{
+						Features:         map[string]bool{"rx-gro": false},
+						CombinedChannels: &channels,
+						Rings:            &types.EthtoolRings{RX: &ringSize},
+					},
+				},
+				PFName:      "enp175s0f1",
+				VFID:        0,
+				ContIFNames: "net1",
+				OrigVfState: types.VfState{
+					HostIFName: "enp175s6",
+				},
+			}
+			mocked = &utilsMocks.Netlink{}
+			mockedEth = &utilsMocks.Ethtool{}
+		})
+		It("Saves original ethtool settings in the host namespace", func() {
+			fakeLink := &FakeLink{netlink.LinkAttrs{Index: 1000, Name: "enp175s6"}}
+			mocked.On("LinkByName", netconf.OrigVfState.HostIFName).Return(fakeLink, nil)
+			mockedEth.On("Features", "enp175s6").Return(map[string]bool{"rx-gro": true, "rx-lro": false}, nil)
+			mockedEth.On("GetChannels", "enp175s6").Return(ethtool.Channels{CombinedCount: uint32(origChannels)}, nil)
+			mockedEth.On("GetRing", "enp175s6").Return(ethtool.Ring{RxPending: uint32(origRingRX), TxPending: 512}, nil)
+			m := manager{nLink: mocked, ethtool: mockedEth}
+			Expect(m.saveVfNetdevState(netconf)).NotTo(HaveOccurred())
+			Expect(netconf.OrigVfState.Ethtool).To(Equal(&types.EthtoolConf{
+				Features:         map[string]bool{"rx-gro": true},
+				CombinedChannels: &origChannels,
+				Rings:            &types.EthtoolRings{RX: &origRingRX},
+			}))
+			mocked.AssertExpectations(t)
+			mockedEth.AssertExpectations(t)
+		})
+		It("Fails to save unsupported feature", func() {
+			fakeLink := &FakeLink{netlink.LinkAttrs{Index: 1000, Name: "enp175s6"}}
+			mocked.On("LinkByName", netconf.OrigVfState.HostIFName).Return(fakeLink, nil)
+			mockedEth.On("Features", "enp175s6").Return(map[string]bool{"rx-lro": false}, nil)
+			m := manager{nLink: mocked, ethtool: mockedEth}
+			Expect(m.saveVfNetdevState(netconf)).To(HaveOccurred())
+		})
+		It("Applies ethtool settings", func() {
+			fakeLink := &FakeLink{netlink.LinkAttrs{Index: 1000, Name: "enp175s6"}}
+			mocked.On("LinkByName", netconf.OrigVfState.HostIFName).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			mockedEth.On("Change", "net1", map[string]bool{"rx-gro": false}).Return(nil)
+			mockedEth.On("GetChannels", "net1").Return(ethtool.Channels{CombinedCount: uint32(origChannels)}, nil)
+			mockedEth.On("SetChannels", "net1", ethtool.Channels{CombinedCount: uint32(channels)}).
+				Return(ethtool.Channels{}, nil)
+			mockedEth.On("GetRing", "net1").Return(ethtool.Ring{RxPending: uint32(origRingRX), TxPending: 512}, nil)
+			mockedEth.On("SetRing", "net1", ethtool.Ring{RxPending: uint32(ringSize), TxPending: 512}).
+				Return(ethtool.Ring{}, nil)
+			m := manager{nLink: mocked, ethtool: mockedEth}
+			_, err := m.SetupVF(netconf, "net1", "dummycid", newFakeNs())
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+			mockedEth.AssertExpectations(t)
+		})
+		It("Restores original ethtool settings", func() {
+			netconf.OrigVfState.Ethtool = &types.EthtoolConf{
+				Features:         map[string]bool{"rx-gro": true},
+				CombinedChannels: &origChannels,
+				Rings:            &types.EthtoolRings{RX: &origRingRX},
+			}
+			contLink := &FakeLink{netlink.LinkAttrs{Index: 1000, Name: "net1"}}
+			mocked.On("LinkByName", "net1").Return(contLink, nil)
+			mocked.On("LinkSetDown", contLink).Return(nil)
+			mocked.On("LinkSetName", contLink, "enp175s6").Return(nil)
+			mocked.On("LinkSetNsFd", contLink, mock.AnythingOfType("int")).Return(nil)
+			mockedEth.On("Change", "enp175s6", map[string]bool{"rx-gro": true}).Return(nil)
+			mockedEth.On("GetChannels", "enp175s6").Return(ethtool.Channels{CombinedCount: uint32(channels)}, nil)
+			mockedEth.On("SetChannels", "enp175s6", ethtool.Channels{CombinedCount: uint32(origChannels)}).
+				Return(ethtool.Channels{}, nil)
+			mockedEth.On("GetRing", "enp175s6").Return(ethtool.Ring{RxPending: uint32(ringSize)}, nil)
+			mockedEth.On("SetRing", "enp175s6", ethtool.Ring{RxPending: uint32(origRingRX)}).
+				Return(ethtool.Ring{}, nil)
+			m := manager{nLink: mocked, ethtool: mockedEth}
+			Expect(m.ReleaseVF(netconf, "net1", "dummycid", newFakeNs())).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+			mockedEth.AssertExpectations(t)
+		})
+	})
+	Context("Checking SetupVF function - sysctl", func() {
+		var (
+			netconf  *types.PluginConf
+			mocked   *utilsMocks.Netlink
+			fakeLink *FakeLink
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID: "0000:af:06.0",
+					Sysctl: map[string]string{
+						"net.ipv6.conf.<if>.accept_ra":    "0",
+						"net.ipv6.conf.<if>.disable_ipv6": "1",
+					},
+				},
+				PFName: "enp175s0f1",
+				VFID:   0,
+				OrigVfState: types.VfState{
+					HostIFName: "enp175s6",
+				},
+			}
+			fakeLink = &FakeLink{netlink.LinkAttrs{Index: 1000, Name: "enp175s6"}}
+			mocked = &utilsMocks.Netlink{}
+			mocked.On("LinkByName", netconf.OrigVfState.HostIFName).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+		})
+		It("Sets sysctls for the container interface", func() {
+			mockedSysctl := &utilsMocks.Sysctl{}
+			mockedSysctl.On("Set", "net.ipv6.conf.net1/100.accept_ra", "0").Return(nil)
+			mockedSysctl.On("Set", "net.ipv6.conf.net1/100.disable_ipv6", "1").Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			m := manager{nLink: mocked, sysctl: mockedSysctl}
+			_, err := m.SetupVF(netconf, "net1.100", "dummycid", newFakeNs())
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+			mockedSysctl.AssertExpectations(t)
+		})
+		It("Fails to set sysctl", func() {
+			mockedSysctl := &utilsMocks.Sysctl{}
+			mockedSysctl.On("Set", mock.Anything, mock.Anything).Return(errors.New("some error"))
+			m := manager{nLink: mocked, sysctl: mockedSysctl}
+			_, err := m.SetupVF(netconf, "net1", "dummycid", newFakeNs())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking SetupVF and ReleaseVF functions - promisc and allmulti", func() {
+		var (
+			netconf *types.PluginConf
+			mocked  *utilsMocks.Netlink
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID: "0000:af:06.0",
+					Promisc:  "on",
+					Allmulti: "on",
+				},
+				PFName:      "enp175s0f1",
+				VFID:        0,
+				ContIFNames: "net1",
+				OrigVfState: types.VfState{
+					HostIFName: "enp175s6",
+				},
+			}
+			mocked = &utilsMocks.Netlink{}
+		})
+		It("Turns on promiscuous and all-multicast modes", func() {
+			fakeLink := &FakeLink{netlink.LinkAttrs{Index: 1000, Name: "enp175s6"}}
+			mocked.On("LinkByName", netconf.OrigVfState.HostIFName).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetPromiscOn", fakeLink).Return(nil)
+			mocked.On("LinkSetAllmulticastOn", fakeLink).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			m := manager{nLink: mocked}
+			_, err := m.SetupVF(netconf, "net1", "dummycid", newFakeNs())
+			Expect(err).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+		It("Fails to turn on promiscuous mode", func() {
+			fakeLink := &FakeLink{netlink.LinkAttrs{Index: 1000, Name: "enp175s6"}}
+			mocked.On("LinkByName", netconf.OrigVfState.HostIFName).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetPromiscOn", fakeLink).Return(errors.New("some error"))
+			m := manager{nLink: mocked}
+			_, err := m.SetupVF(netconf, "net1", "dummycid", newFakeNs())
+			Expect(err).To(HaveOccurred())
+		})
+		It("Clears promiscuous and all-multicast modes", func() {
+			contLink := &FakeLink{netlink.LinkAttrs{Index: 1000, Name: "net1", Promisc: 1, Allmulti: 1}}
+			mocked.On("LinkByName", "net1").Return(contLink, nil)
+			mocked.On("LinkSetDown", contLink).Return(nil)
+			mocked.On("LinkSetName", contLink, "enp175s6").Return(nil)
+			mocked.On("LinkSetPromiscOff", contLink).Return(nil)
+			mocked.On("LinkSetAllmulticastOff", contLink).Return(nil)
+			mocked.On("LinkSetNsFd", contLink, mock.AnythingOfType("int")).Return(nil)
+			m := manager{nLink: mocked}
+			Expect(m.ReleaseVF(netconf, "net1", "dummycid", newFakeNs())).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking SetupVF and ReleaseVF functions - VLAN sub-interfaces", func() {
+		var (
+			netconf *types.PluginConf
+			mocked  *utilsMocks.Netlink
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID: "0000:af:06.0",
+					VlanInterfaces: []types.VlanInterface{
+						{ID: 100},
+						{ID: 200, Name: "<if>-v200"},
+					},
+				},
+				PFName:      "enp175s0f1",
+				VFID:        0,
+				ContIFNames: "net1",
+				OrigVfState: types.VfState{
+					HostIFName: "enp175s6",
+				},
+			}
+			mocked = &utilsMocks.Netlink{}
+		})
+		isVlan := func(name string, id int) interface{} {
+			return mock.MatchedBy(func(link netlink.Link) bool {
+				vlan, ok := link.(*netlink.Vlan)
+				return ok && vlan.Name == name && vlan.VlanId == id && vlan.ParentIndex == 1000
+			})
+		}
+		It("Creates VLAN sub-interfaces", func() {
+			fakeLink := &FakeLink{netlink.LinkAttrs{Index: 1000, Name: "enp175s6"}}
+			mocked.On("LinkByName", netconf.OrigVfState.HostIFName).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			mocked.On("LinkAdd", isVlan("net1.100", 100)).Return(nil).Once()
+			mocked.On("LinkSetUp", isVlan("net1.100", 100)).Return(nil).Once()
+			mocked.On("LinkAdd", isVlan("net1-v200", 200)).Return(nil).Once()
+			mocked.On("LinkSetUp", isVlan("net1-v200", 200)).Return(nil).Once()
+			m := manager{nLink: mocked}
+			_, err := m.SetupVF(netconf, "net1", "dummycid", newFakeNs())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(netconf.ContVlanIfNames).To(Equal([]string{"net1.100", "net1-v200"}))
+			mocked.AssertExpectations(t)
+		})
+		It("Saves names of created VLAN sub-interfaces on failure", func() {
+			fakeLink := &FakeLink{netlink.LinkAttrs{Index: 1000, Name: "enp175s6"}}
+			mocked.On("LinkByName", netconf.OrigVfState.HostIFName).Return(fakeLink, nil)
+			mocked.On("LinkSetDown", fakeLink).Return(nil)
+			mocked.On("LinkSetName", fakeLink, mock.Anything).Return(nil)
+			mocked.On("LinkSetNsFd", fakeLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkSetUp", fakeLink).Return(nil)
+			mocked.On("LinkAdd", isVlan("net1.100", 100)).Return(nil).Once()
+			mocked.On("LinkSetUp", isVlan("net1.100", 100)).Return(nil).Once()
+			mocked.On("LinkAdd", isVlan("net1-v200", 200)).Return(errors.New("some error")).Once()
+			m := manager{nLink: mocked}
+			_, err := m.SetupVF(netconf, "net1", "dummycid", newFakeNs())
+			Expect(err).To(HaveOccurred())
+			Expect(netconf.ContVlanIfNames).To(Equal([]string{"net1.100"}))
+		})
+		It("Deletes VLAN sub-interfaces", func() {
+			netconf.ContVlanIfNames = []string{"net1.100", "net1-v200"}
+			contLink := &FakeLink{netlink.LinkAttrs{Index: 1000, Name: "net1"}}
+			vlanLink := &netlink.Vlan{LinkAttrs: netlink.LinkAttrs{Name: "net1.100", ParentIndex: 1000}, VlanId: 100}
+			mocked.On("LinkByName", "net1").Return(contLink, nil)
+			mocked.On("LinkByName", "net1.100").Return(vlanLink, nil)
+			mocked.On("LinkByName", "net1-v200").Return(nil, errors.New("not found"))
+			mocked.On("LinkDel", vlanLink).Return(nil).Once()
+			mocked.On("LinkSetDown", contLink).Return(nil)
+			mocked.On("LinkSetName", contLink, "enp175s6").Return(nil)
+			mocked.On("LinkSetNsFd", contLink, mock.AnythingOfType("int")).Return(nil)
+			m := manager{nLink: mocked}
+			Expect(m.ReleaseVF(netconf, "net1", "dummycid", newFakeNs())).NotTo(HaveOccurred())
+			Expect(netconf.ContVlanIfNames).To(BeEmpty())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking ResetVFConfig function - complete saved state", func() {
+		var (
+			netconf  *types.PluginConf
+			mocked   *utilsMocks.Netlink
+			fakeLink *FakeLink
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID: "0000:af:06.0",
+				},
+				PFName: "enp175s0f1",
+				VFID:   3,
+				OrigVfState: types.VfState{
+					AdminMAC:  "00:00:00:00:00:00",
+					SpoofChk:  true,
+					LinkState: netlink.VF_LINK_STATE_AUTO,
+					Saved:     true,
+				},
+			}
+			fakeLink = &FakeLink{netlink.LinkAttrs{Vfs: []netlink.VfInfo{{
+				ID:        3,
+				Mac:       net.HardwareAddr{0, 0, 0, 0, 0, 0},
+				Spoofchk:  true,
+				LinkState: netlink.VF_LINK_STATE_AUTO,
+			}}}}
+			mocked = &utilsMocks.Netlink{}
+			mocked.On("LinkByName", netconf.PFName).Return(fakeLink, nil)
+		})
+		It("Does not change VF options which were not changed after the state was saved", func() {
+			m := manager{nLink: mocked}
+			Expect(m.ResetVFConfig(netconf)).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+		It("Restores VF options which were changed after the state was saved", func() {
+			fakeLink.Vfs[0].Trust = 1
+			fakeLink.Vfs[0].Spoofchk = false
+			fakeLink.Vfs[0].MaxTxRate = 1000
+			mocked.On("LinkSetVfRate", fakeLink, netconf.VFID, 0, 0).Return(nil).Once()
+			mocked.On("LinkSetVfTrust", fakeLink, netconf.VFID, false).Return(nil).Once()
+			mocked.On("LinkSetVfSpoofchk", fakeLink, netconf.VFID, true).Return(nil).Once()
+			m := manager{nLink: mocked}
+			Expect(m.ResetVFConfig(netconf)).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking SaveState and ReleaseVF functions - complete saved state", func() {
+		var (
+			netconf *types.PluginConf
+			mocked  *utilsMocks.Netlink
+			origMac net.HardwareAddr
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID: "0000:af:06.0",
+				},
+				PFName:      "enp175s0f1",
+				VFID:        0,
+				ContIFNames: "net1",
+				OrigVfState: types.VfState{
+					HostIFName: "enp175s6",
+				},
+			}
+			origMac, _ = net.ParseMAC("6e:16:06:0e:b7:e9")
+			mocked = &utilsMocks.Netlink{}
+		})
+		It("Saves VF netdevice state", func() {
+			fakeLink := &FakeLink{netlink.LinkAttrs{
+				Index:        1000,
+				Name:         "enp175s6",
+				HardwareAddr: origMac,
+				MTU:          1500,
+				Flags:        net.FlagUp,
+				Promisc:      1,
+			}}
+			mocked.On("LinkByName", netconf.OrigVfState.HostIFName).Return(fakeLink, nil)
+			m := manager{nLink: mocked}
+			Expect(m.saveVfNetdevState(netconf)).NotTo(HaveOccurred())
+			Expect(netconf.OrigVfState).To(Equal(types.VfState{
+				HostIFName:   "enp175s6",
+				EffectiveMAC: origMac.String(),
+				MTU:          1500,
+				AdminUp:      true,
+				Promisc:      true,
+				NetdevSaved:  true,
+			}))
+		})
+		It("Restores VF netdevice state which was changed after the state was saved", func() {
+			netconf.OrigVfState.EffectiveMAC = origMac.String()
+			netconf.OrigVfState.MTU = 1500
+			netconf.OrigVfState.AdminUp = true
+			netconf.OrigVfState.Allmulti = true
+			netconf.OrigVfState.NetdevSaved = true
+			contLink := &FakeLink{netlink.LinkAttrs{
+				Index:        1000,
+				Name:         "net1",
+				HardwareAddr: origMac,
+				MTU:          9000,
+				Promisc:      1,
+			}}
+			hostLink := &FakeLink{netlink.LinkAttrs{Index: 1000, Name: "enp175s6"}}
+			mocked.On("LinkByName", "net1").Return(contLink, nil)
+			mocked.On("LinkSetDown", contLink).Return(nil)
+			mocked.On("LinkSetName", contLink, "enp175s6").Return(nil)
+			mocked.On("LinkSetMTU", contLink, 1500).Return(nil)
+			mocked.On("LinkSetPromiscOff", contLink).Return(nil)
+			mocked.On("LinkSetAllmulticastOn", contLink).Return(nil)
+			mocked.On("LinkSetNsFd", contLink, mock.AnythingOfType("int")).Return(nil)
+			mocked.On("LinkByName", "enp175s6").Return(hostLink, nil)
+			mocked.On("LinkSetUp", hostLink).Return(nil)
+			m := manager{nLink: mocked}
+			Expect(m.ReleaseVF(netconf, "net1", "dummycid", newFakeNs())).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+	})
+	Context("Checking SaveState and DetachRepresentor functions - complete saved state", func() {
+		var (
+			netconf    *types.PluginConf
+			mockedNl   *utilsMocks.Netlink
+			origMaster *netlink.Bridge
+			fakeLink   *FakeLink
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID: "0000:af:06.0",
+				},
+				Representor:  "dummylink",
+				PFName:       "enp175s0f1",
+				ActualBridge: "bridge1",
+				VFID:         0,
+			}
+			origMaster = &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 2000, Name: "bridge2"}}
+			fakeLink = &FakeLink{netlink.LinkAttrs{Name: netconf.Representor, MTU: 1500}}
+			mockedNl = &utilsMocks.Netlink{}
+			mockedNl.On("LinkByName", netconf.Representor).Return(fakeLink, nil).Maybe()
+			// Mute logger
+			zerolog.SetGlobalLevel(zerolog.Disabled)
+		})
+		It("Saves representor state", func() {
+			fakeLink.Flags = net.FlagUp
+			fakeLink.MasterIndex = origMaster.Index
+			portFlags := netlink.Protinfo{Learning: true, Flood: true}
+			mockedNl.On("LinkByIndex", origMaster.Index).Return(origMaster, nil)
+			mockedNl.On("LinkGetProtinfo", fakeLink).Return(portFlags, nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.saveRepState(netconf, fakeLink)).NotTo(HaveOccurred())
+			Expect(netconf.OrigRepState).To(Equal(types.RepState{
+				MTU:       1500,
+				AdminUp:   true,
+				Master:    "bridge2",
+				PortFlags: &portFlags,
+				Saved:     true,
+			}))
+			mockedNl.AssertExpectations(t)
+		})
+		It("Restores representor state", func() {
+			portFlags := netlink.Protinfo{Learning: true, Flood: true}
+			fakeLink.MTU = 9000
+			netconf.OrigRepState = types.RepState{
+				MTU:       1500,
+				AdminUp:   true,
+				Master:    "bridge2",
+				PortFlags: &portFlags,
+				Saved:     true,
+			}
+			mockedNl.On("LinkSetDown", fakeLink).Return(nil)
+			mockedNl.On("LinkSetMTU", fakeLink, 1500).Return(nil)
+			mockedNl.On("LinkSetNoMaster", fakeLink).Return(nil)
+			mockedNl.On("LinkByName", "bridge2").Return(origMaster, nil)
+			mockedNl.On("LinkSetMaster", fakeLink, origMaster).Return(nil)
+			mockedNl.On("LinkSetProtinfo", fakeLink, portFlags).Return(nil)
+			mockedNl.On("LinkSetUp", fakeLink).Return(nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.DetachRepresentor(netconf)).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+		It("Does not restore representor state saved while it was attached to the bridge", func() {
+			netconf.OrigRepState = types.RepState{
+				MTU:     1500,
+				AdminUp: true,
+				Master:  netconf.ActualBridge,
+				Saved:   true,
+			}
+			mockedNl.On("LinkSetDown", fakeLink).Return(nil)
+			mockedNl.On("LinkSetNoMaster", fakeLink).Return(nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.DetachRepresentor(netconf)).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+	})
+	Context("Checking AttachRepresentor and DetachRepresentor functions - alias", func() {
+		var (
+			netconf    *types.PluginConf
+			mockedNl   *utilsMocks.Netlink
+			fakeBridge *netlink.Bridge
+			fakeLink   *FakeLink
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID: "0000:af:06.0",
+				},
+				Representor:  "dummylink",
+				PFName:       "enp175s0f1",
+				ActualBridge: "bridge1",
+				VFID:         0,
+				RepAlias:     "accelerated-bridge: network=mynet container=cid pod=default/pod1",
+				RepAltName:   "default_pod1_net1",
+				OrigRepState: types.RepState{Alias: "old alias"},
+			}
+			fakeBridge = &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "bridge1"}}
+			fakeLink = &FakeLink{netlink.LinkAttrs{Name: netconf.Representor, Alias: "old alias"}}
+			mockedNl = &utilsMocks.Netlink{}
+			mockedNl.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
+			// Mute logger
+			zerolog.SetGlobalLevel(zerolog.Disabled)
+		})
+		It("Sets alias and adds altname", func() {
+			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
+			mockedNl.On("LinkSetUp", fakeLink).Return(nil)
+			mockedNl.On("LinkSetMaster", fakeLink, fakeBridge).Return(nil)
+			mockedNl.On("LinkSetAlias", fakeLink, netconf.RepAlias).Return(nil)
+			mockedNl.On("LinkAddAltName", fakeLink, netconf.RepAltName).Return(nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.AttachRepresentor(netconf)).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+		It("Fails to add altname, should restore alias before representor is changed", func() {
+			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
+			mockedNl.On("LinkSetAlias", fakeLink, netconf.RepAlias).Return(nil).Once()
+			mockedNl.On("LinkAddAltName", fakeLink, netconf.RepAltName).Return(errors.New("some error"))
+			mockedNl.On("LinkDelAltName", fakeLink, netconf.RepAltName).Return(errors.New("not found"))
+			mockedNl.On("LinkSetAlias", fakeLink, "old alias").Return(nil).Once()
+			m := manager{nLink: mockedNl}
+			Expect(m.AttachRepresentor(netconf)).To(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+		It("Fails to attach representor to the bridge, should delete altname and restore alias", func() {
+			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
+			mockedNl.On("LinkSetAlias", fakeLink, netconf.RepAlias).Return(nil).Once()
+			mockedNl.On("LinkAddAltName", fakeLink, netconf.RepAltName).Return(nil)
+			mockedNl.On("LinkSetUp", fakeLink).Return(nil)
+			mockedNl.On("LinkSetMaster", fakeLink, fakeBridge).Return(errors.New("some error"))
+			mockedNl.On("LinkDelAltName", fakeLink, netconf.RepAltName).Return(nil)
+			mockedNl.On("LinkSetAlias", fakeLink, "old alias").Return(nil).Once()
+			m := manager{nLink: mockedNl}
+			Expect(m.AttachRepresentor(netconf)).To(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+		It("Deletes altname and restores original alias", func() {
+			fakeLink.Alias = netconf.RepAlias
+			mockedNl.On("LinkSetDown", fakeLink).Return(nil)
+			mockedNl.On("LinkSetNoMaster", fakeLink).Return(nil)
+			mockedNl.On("LinkDelAltName", fakeLink, netconf.RepAltName).Return(errors.New("not found"))
+			mockedNl.On("LinkSetAlias", fakeLink, "old alias").Return(nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.DetachRepresentor(netconf)).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+	})
+	Context("Checking AttachRepresentor and DetachRepresentor functions - bridge port attributes", func() {
+		var (
+			netconf    *types.PluginConf
+			mockedNl   *utilsMocks.Netlink
+			fakeBridge *netlink.Bridge
+			fakeLink   *FakeLink
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID: "0000:af:06.0",
+					Isolated: true,
+				},
+				Representor:  "dummylink",
+				PFName:       "enp175s0f1",
+				ActualBridge: "bridge1",
+				VFID:         0,
+			}
+			fakeBridge = &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "bridge1"}}
+			fakeLink = &FakeLink{netlink.LinkAttrs{Name: netconf.Representor}}
+			mockedNl = &utilsMocks.Netlink{}
+			mockedNl.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
+			// Mute logger
+			zerolog.SetGlobalLevel(zerolog.Disabled)
+		})
+		attachToBridge := func() {
+			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
+			mockedNl.On("LinkSetUp", fakeLink).Return(nil)
+			mockedNl.On("LinkSetMaster", fakeLink, fakeBridge).Return(nil)
+		}
+		It("Isolates bridge port", func() {
+			attachToBridge()
+			mockedNl.On("LinkSetIsolated", fakeLink, true).Return(nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.AttachRepresentor(netconf)).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+		It("Fails to isolate bridge port", func() {
+			attachToBridge()
+			mockedNl.On("LinkSetIsolated", fakeLink, true).Return(errors.New("some error"))
+			mockedNl.On("LinkSetNoMaster", fakeLink).Return(nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.AttachRepresentor(netconf)).To(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+		It("Does not touch isolated flag if the option is not set", func() {
+			netconf.Isolated = false
+			attachToBridge()
+			m := manager{nLink: mockedNl}
+			Expect(m.AttachRepresentor(netconf)).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+		It("Sets bridge port flags", func() {
+			netconf.Isolated = false
+			netconf.PortFlags = &types.PortFlags{Flood: boolPtr(false), BcastFlood: boolPtr(false)}
+			attachToBridge()
+			mockedNl.On("LinkSetFlood", fakeLink, false).Return(nil).Once()
+			mockedNl.On("LinkSetBcastFlood", fakeLink, false).Return(nil).Once()
+			m := manager{nLink: mockedNl}
+			Expect(m.AttachRepresentor(netconf)).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+		It("Fails to set bridge port flags", func() {
+			netconf.Isolated = false
+			netconf.PortFlags = &types.PortFlags{McastFlood: boolPtr(false)}
+			attachToBridge()
+			mockedNl.On("LinkSetMcastFlood", fakeLink, false).Return(errors.New("some error")).Once()
+			mockedNl.On("LinkSetNoMaster", fakeLink).Return(nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.AttachRepresentor(netconf)).To(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+		It("Enables neigh_suppress on bridge port", func() {
+			netconf.Isolated = false
+			netconf.NeighSuppress = true
+			attachToBridge()
+			mockedNl.On("LinkSetBrNeighSuppress", fakeLink, true).Return(nil).Once()
+			m := manager{nLink: mockedNl}
+			Expect(m.AttachRepresentor(netconf)).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+		It("Applies STP port settings", func() {
+			netconf.Isolated = false
+			netconf.BpduGuard = true
+			netconf.RootBlock = true
+			netconf.Cost = intPtr(100)
+			netconf.Priority = intPtr(16)
+			attachToBridge()
+			mockedNl.On("LinkSetGuard", fakeLink, true).Return(nil).Once()
+			mockedNl.On("LinkSetRootBlock", fakeLink, true).Return(nil).Once()
+			mockedNl.On("LinkSetPortCost", fakeLink, uint32(100)).Return(nil).Once()
+			mockedNl.On("LinkSetPortPriority", fakeLink, uint16(16)).Return(nil).Once()
+			m := manager{nLink: mockedNl}
+			Expect(m.AttachRepresentor(netconf)).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+		It("Fails to enable BPDU guard", func() {
+			netconf.Isolated = false
+			netconf.BpduGuard = true
+			attachToBridge()
+			mockedNl.On("LinkSetGuard", fakeLink, true).Return(errors.New("some error")).Once()
+			mockedNl.On("LinkSetNoMaster", fakeLink).Return(nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.AttachRepresentor(netconf)).To(MatchError(ContainSubstring("BPDU guard")))
+			mockedNl.AssertExpectations(t)
+		})
+		It("Does not restore isolated flag, the bridge port is destroyed on detach", func() {
+			mockedNl.On("LinkSetDown", fakeLink).Return(nil)
+			mockedNl.On("LinkSetNoMaster", fakeLink).Return(nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.DetachRepresentor(netconf)).NotTo(HaveOccurred())
+			mockedNl.AssertNotCalled(t, "LinkSetIsolated", fakeLink, false)
+			mockedNl.AssertExpectations(t)
+		})
+		It("Does not restore port flags, the bridge port is destroyed on detach", func() {
+			netconf.Isolated = false
+			netconf.PortFlags = &types.PortFlags{Learning: boolPtr(false), McastFlood: boolPtr(false)}
+			mockedNl.On("LinkSetDown", fakeLink).Return(nil)
+			mockedNl.On("LinkSetNoMaster", fakeLink).Return(nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.DetachRepresentor(netconf)).NotTo(HaveOccurred())
+			mockedNl.AssertNotCalled(t, "LinkSetLearning", fakeLink, true)
+			mockedNl.AssertNotCalled(t, "LinkSetMcastFlood", fakeLink, true)
+			mockedNl.AssertExpectations(t)
+		})
+		It("Does not restore bridge port attributes which are reset on detach", func() {
+			netconf.NeighSuppress = true
+			netconf.PortFlags = &types.PortFlags{Flood: boolPtr(false)}
+			netconf.BpduGuard = true
+			netconf.RootBlock = true
+			netconf.Cost = intPtr(100)
+			netconf.Priority = intPtr(16)
+			mockedNl.On("LinkSetDown", fakeLink).Return(nil)
+			mockedNl.On("LinkSetNoMaster", fakeLink).Return(nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.DetachRepresentor(netconf)).NotTo(HaveOccurred())
+			mockedNl.AssertExpectations(t)
+		})
+	})
+	Context("Checking AddBridgeNeighbors and DetachRepresentor functions - bridge neighbor entries", func() {
+		var (
+			netconf    *types.PluginConf
+			mockedNl   *utilsMocks.Netlink
+			fakeBridge *netlink.Bridge
+			fakeLink   *FakeLink
+			ips        []net.IP
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID:      "0000:af:06.0",
+					NeighSuppress: true,
+				},
+				Representor:  "dummylink",
+				PFName:       "enp175s0f1",
+				ActualBridge: "bridge1",
+				VFID:         0,
+			}
+			fakeBridge = &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "bridge1"}}
+			fakeLink = &FakeLink{netlink.LinkAttrs{Name: netconf.Representor, Index: 10}}
+			ips = []net.IP{net.ParseIP("10.56.217.10"), net.ParseIP("fd00::10")}
+			mockedNl = &utilsMocks.Netlink{}
+			mockedNl.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
+			// Mute logger
+			zerolog.SetGlobalLevel(zerolog.Disabled)
+		})
+		isNeighEntry := func(linkIndex, family int, ip string) interface{} {
+			return mock.MatchedBy(func(neigh *netlink.Neigh) bool {
+				return neigh.LinkIndex == linkIndex && neigh.Family == family && neigh.IP.Equal(net.ParseIP(ip)) &&
+					neigh.State == netlink.NUD_PERMANENT
+			})
+		}
+		It("Adds neighbor entries to the bridge", func() {
+			mockedNl.On("NeighSet", isNeighEntry(1000, unix.AF_INET, "10.56.217.10")).Return(nil).Once()
+			mockedNl.On("NeighSet", isNeighEntry(1000, unix.AF_INET6, "fd00::10")).Return(nil).Once()
+			m := manager{nLink: mockedNl}
+			Expect(m.AddBridgeNeighbors(netconf, "6e:16:06:0e:b7:e9", ips)).NotTo(HaveOccurred())
+			Expect(netconf.BridgeNeighbors).To(Equal([]types.NeighEntry{
+				{Dev: "bridge1", IP: "10.56.217.10", MAC: "6e:16:06:0e:b7:e9"},
+				{Dev: "bridge1", IP: "fd00::10", MAC: "6e:16:06:0e:b7:e9"}}))
+			mockedNl.AssertExpectations(t)
+		})
+		It("Adds neighbor entries to the bridge VLAN device of the PVID", func() {
+			netconf.Vlan = 100
+			vlanDev := &netlink.Vlan{LinkAttrs: netlink.LinkAttrs{Index: 1001, Name: "bridge1.100", ParentIndex: 1000},
+				VlanId: 100}
+			otherVlanDev := &netlink.Vlan{LinkAttrs: netlink.LinkAttrs{Index: 1002, Name: "bridge1.200",
+				ParentIndex: 1000}, VlanId: 200}
+			mockedNl.On("LinkList").Return([]netlink.Link{fakeBridge, otherVlanDev, vlanDev}, nil)
+			mockedNl.On("NeighSet", isNeighEntry(1001, unix.AF_INET, "10.56.217.10")).Return(nil).Once()
+			m := manager{nLink: mockedNl}
+			Expect(m.AddBridgeNeighbors(netconf, "6e:16:06:0e:b7:e9", ips[:1])).NotTo(HaveOccurred())
+			Expect(netconf.BridgeNeighbors).To(Equal([]types.NeighEntry{
+				{Dev: "bridge1.100", IP: "10.56.217.10", MAC: "6e:16:06:0e:b7:e9"}}))
+			mockedNl.AssertExpectations(t)
+		})
+		It("Skips neighbor entries if the bridge has no VLAN device for the PVID", func() {
+			netconf.Vlan = 100
+			mockedNl.On("LinkList").Return([]netlink.Link{fakeBridge}, nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.AddBridgeNeighbors(netconf, "6e:16:06:0e:b7:e9", ips)).NotTo(HaveOccurred())
+			Expect(netconf.BridgeNeighbors).To(BeEmpty())
+			mockedNl.AssertNotCalled(t, "NeighSet", mock.Anything)
+		})
+		It("Fails to add neighbor entry", func() {
+			mockedNl.On("NeighSet", mock.Anything).Return(errors.New("some error"))
+			m := manager{nLink: mockedNl}
+			Expect(m.AddBridgeNeighbors(netconf, "6e:16:06:0e:b7:e9", ips)).To(HaveOccurred())
+			Expect(netconf.BridgeNeighbors).To(BeEmpty())
+		})
+		It("Deletes added neighbor entries", func() {
+			netconf.NeighSuppress = false
+			netconf.BridgeNeighbors = []types.NeighEntry{
+				{Dev: "bridge1", IP: "10.56.217.10", MAC: "6e:16:06:0e:b7:e9"},
+				{Dev: "bridge1", IP: "fd00::10", MAC: "6e:16:06:0e:b7:e9"}}
+			mockedNl.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
+			mockedNl.On("LinkSetDown", fakeLink).Return(nil)
+			mockedNl.On("NeighDel", isNeighEntry(1000, unix.AF_INET, "10.56.217.10")).Return(nil).Once()
+			mockedNl.On("NeighDel", isNeighEntry(1000, unix.AF_INET6, "fd00::10")).
+				Return(errors.New("not found")).Once()
+			mockedNl.On("LinkSetNoMaster", fakeLink).Return(nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.DetachRepresentor(netconf)).NotTo(HaveOccurred())
+			Expect(netconf.BridgeNeighbors).To(BeEmpty())
+			mockedNl.AssertExpectations(t)
+		})
+	})
+	Context("Checking AddRepresentorFDB and DetachRepresentor functions - static FDB entries", func() {
+		var (
+			netconf  *types.PluginConf
+			mockedNl *utilsMocks.Netlink
+			fakeLink *FakeLink
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID:  "0000:af:06.0",
+					Vlan:      100,
+					StaticFDB: true,
+				},
+				Representor:  "dummylink",
+				PFName:       "enp175s0f1",
+				ActualBridge: "bridge1",
+				VFID:         0,
+				Trunk:        []int{4, 6},
+			}
+			fakeLink = &FakeLink{netlink.LinkAttrs{Name: netconf.Representor, Index: 10}}
+			mockedNl = &utilsMocks.Netlink{}
+			mockedNl.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
+			// Mute logger
+			zerolog.SetGlobalLevel(zerolog.Disabled)
+		})
+		isFdbEntry := func(mac string, vlan int) interface{} {
+			return mock.MatchedBy(func(neigh *netlink.Neigh) bool {
+				return neigh.HardwareAddr.String() == mac && neigh.Vlan == vlan && neigh.LinkIndex == 10 &&
+					neigh.Family == unix.AF_BRIDGE && neigh.Flags&netlink.NTF_MASTER != 0 &&
+					neigh.State == netlink.NUD_NOARP
+			})
+		}
+		It("Adds static FDB entries for PVID and trunk VLANs", func() {
+			mockedNl.On("NeighSet", isFdbEntry("6e:16:06:0e:b7:e9", 100)).Return(nil).Once()
+			mockedNl.On("NeighSet", isFdbEntry("6e:16:06:0e:b7:e9", 4)).Return(nil).Once()
+			mockedNl.On("NeighSet", isFdbEntry("6e:16:06:0e:b7:e9", 6)).Return(nil).Once()
+			m := manager{nLink: mockedNl}
+			Expect(m.AddRepresentorFDB(netconf, "6E:16:06:0E:B7:E9")).NotTo(HaveOccurred())
+			Expect(netconf.RepFdbEntries).To(Equal([]types.FdbEntry{
+				{MAC: "6e:16:06:0e:b7:e9", Vlan: 100},
+				{MAC: "6e:16:06:0e:b7:e9", Vlan: 4},
+				{MAC: "6e:16:06:0e:b7:e9", Vlan: 6}}))
+			mockedNl.AssertExpectations(t)
+		})
+		It("Adds static FDB entry without VLAN if VF has no VLAN config", func() {
+			netconf.Vlan = 0
+			netconf.Trunk = nil
+			mockedNl.On("NeighSet", isFdbEntry("6e:16:06:0e:b7:e9", 0)).Return(nil).Once()
+			m := manager{nLink: mockedNl}
+			Expect(m.AddRepresentorFDB(netconf, "6e:16:06:0e:b7:e9")).NotTo(HaveOccurred())
+			Expect(netconf.RepFdbEntries).To(Equal([]types.FdbEntry{{MAC: "6e:16:06:0e:b7:e9", Vlan: 0}}))
+			mockedNl.AssertExpectations(t)
+		})
+		It("Saves added FDB entries on failure", func() {
+			mockedNl.On("NeighSet", isFdbEntry("6e:16:06:0e:b7:e9", 100)).Return(nil).Once()
+			mockedNl.On("NeighSet", isFdbEntry("6e:16:06:0e:b7:e9", 4)).Return(errors.New("some error")).Once()
+			m := manager{nLink: mockedNl}
+			Expect(m.AddRepresentorFDB(netconf, "6e:16:06:0e:b7:e9")).To(HaveOccurred())
+			Expect(netconf.RepFdbEntries).To(Equal([]types.FdbEntry{{MAC: "6e:16:06:0e:b7:e9", Vlan: 100}}))
+		})
+		It("Invalid MAC address", func() {
+			m := manager{nLink: mockedNl}
+			Expect(m.AddRepresentorFDB(netconf, "invalid")).To(HaveOccurred())
+		})
+		It("Deletes added FDB entries", func() {
+			netconf.RepFdbEntries = []types.FdbEntry{
+				{MAC: "6e:16:06:0e:b7:e9", Vlan: 100},
+				{MAC: "6e:16:06:0e:b7:e9", Vlan: 4}}
+			mockedNl.On("LinkSetDown", fakeLink).Return(nil)
+			mockedNl.On("NeighDel", isFdbEntry("6e:16:06:0e:b7:e9", 100)).Return(nil).Once()
+			mockedNl.On("NeighDel", isFdbEntry("6e:16:06:0e:b7:e9", 4)).Return(errors.New("not found")).Once()
+			mockedNl.On("LinkSetNoMaster", fakeLink).Return(nil)
+			m := manager{nLink: mockedNl}
+			Expect(m.DetachRepresentor(netconf)).NotTo(HaveOccurred())
+			Expect(netconf.RepFdbEntries).To(BeEmpty())
+			mockedNl.AssertExpectations(t)
+		})
+	})
+	Context("Checking CheckRepresentor function", func() {
+		var (
+			netconf    *types.PluginConf
+			fakeBridge *netlink.Bridge
+			fakeLink   *FakeLink
+			mocked     *utilsMocks.Netlink
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID: "0000:af:06.0",
+					Vlan:     100,
+				},
+				Representor:  "dummylink",
+				PFName:       "enp175s0f1",
+				ActualBridge: "bridge1",
+				VFID:         0,
+				Trunk:        []int{4, 6},
+			}
+			fakeBridge = &netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "bridge1"}}
+			fakeLink = &FakeLink{netlink.LinkAttrs{
+				Name:        netconf.Representor,
+				Index:       10,
+				MasterIndex: 1000,
+				Flags:       net.FlagUp,
+			}}
+			mocked = &utilsMocks.Netlink{}
+			mocked.On("LinkByName", netconf.Representor).Return(fakeLink, nil)
+			mocked.On("LinkByName", netconf.ActualBridge).Return(fakeBridge, nil)
+		})
+		It("Representor has expected configuration (success)", func() {
+			mocked.On("BridgeVlanList").Return(map[int32][]*nl.BridgeVlanInfo{
+				10: {{Flags: nl.BRIDGE_VLAN_INFO_PVID | nl.BRIDGE_VLAN_INFO_UNTAGGED, Vid: 100},
+					{Flags: 0, Vid: 4},
+					{Flags: 0, Vid: 6}},
+			}, nil)
+			m := manager{nLink: mocked}
+			Expect(m.CheckRepresentor(netconf)).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+		It("Representor is not attached to the bridge (failure)", func() {
+			fakeLink.MasterIndex = 0
+			m := manager{nLink: mocked}
+			err := m.CheckRepresentor(netconf)
+			Expect(err).To(MatchError(ContainSubstring("not attached")))
+			Expect(errors.Is(err, ErrStateMismatch)).To(BeTrue())
+		})
+		It("Representor is down (failure)", func() {
+			fakeLink.Flags = 0
+			m := manager{nLink: mocked}
+			Expect(m.CheckRepresentor(netconf)).To(MatchError(ContainSubstring("is down")))
+		})
+		It("Representor doesn't exist (failure)", func() {
+			mocked = &utilsMocks.Netlink{}
+			mocked.On("LinkByName", netconf.Representor).Return(nil, netlink.LinkNotFoundError{})
+			m := manager{nLink: mocked}
+			Expect(errors.Is(m.CheckRepresentor(netconf), ErrStateMismatch)).To(BeTrue())
+		})
+		It("Failed to get representor (failure)", func() {
+			mocked = &utilsMocks.Netlink{}
+			mocked.On("LinkByName", netconf.Representor).Return(nil, errors.New("some error"))
+			m := manager{nLink: mocked}
+			err := m.CheckRepresentor(netconf)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, ErrStateMismatch)).To(BeFalse())
+		})
+		It("Representor bridge port is not isolated (failure)", func() {
+			netconf.Isolated = true
+			mocked.On("LinkGetBridgePortInfo", fakeLink).Return(types.BridgePortInfo{}, nil)
+			m := manager{nLink: mocked}
+			Expect(m.CheckRepresentor(netconf)).To(MatchError(ContainSubstring("not isolated")))
+		})
+		It("Representor bridge port has neigh_suppress disabled (failure)", func() {
+			netconf.NeighSuppress = true
+			mocked.On("LinkGetBridgePortInfo", fakeLink).Return(types.BridgePortInfo{}, nil)
+			m := manager{nLink: mocked}
+			Expect(m.CheckRepresentor(netconf)).To(MatchError(ContainSubstring("neigh_suppress disabled")))
+		})
+		It("Representor bridge port has unexpected STP cost (failure)", func() {
+			netconf.BpduGuard = true
+			netconf.Cost = intPtr(100)
+			mocked.On("LinkGetBridgePortInfo", fakeLink).Return(
+				types.BridgePortInfo{Protinfo: netlink.Protinfo{Guard: true}, Cost: 2}, nil)
+			m := manager{nLink: mocked}
+			Expect(m.CheckRepresentor(netconf)).To(MatchError(ContainSubstring("STP cost 2, expected 100")))
+		})
+		It("Representor bridge port has unexpected flags (failure)", func() {
+			netconf.PortFlags = &types.PortFlags{Flood: boolPtr(false)}
+			mocked.On("LinkGetBridgePortInfo", fakeLink).Return(
+				types.BridgePortInfo{Protinfo: netlink.Protinfo{Flood: true}}, nil)
+			m := manager{nLink: mocked}
+			Expect(m.CheckRepresentor(netconf)).To(MatchError(ContainSubstring("flags mismatch")))
+		})
+		It("Representor has unexpected VLAN (failure)", func() {
+			mocked.On("BridgeVlanList").Return(map[int32][]*nl.BridgeVlanInfo{
+				10: {{Flags: nl.BRIDGE_VLAN_INFO_PVID | nl.BRIDGE_VLAN_INFO_UNTAGGED, Vid: 100},
+					{Flags: 0, Vid: 4},
+					{Flags: 0, Vid: 6},
+					{Flags: 0, Vid: 7}},
+			}, nil)
+			m := manager{nLink: mocked}
+			Expect(m.CheckRepresentor(netconf)).To(MatchError(ContainSubstring("unexpected VLAN 7")))
+		})
+		It("Representor has VLAN with wrong flags (failure)", func() {
+			mocked.On("BridgeVlanList").Return(map[int32][]*nl.BridgeVlanInfo{
+				10: {{Flags: 0, Vid: 100},
+					{Flags: 0, Vid: 4},
+					{Flags: 0, Vid: 6}},
+			}, nil)
+			m := manager{nLink: mocked}
+			Expect(m.CheckRepresentor(netconf)).To(MatchError(ContainSubstring("VLAN 100")))
+		})
+	})
+	Context("Checking GetHostInterfaces function", func() {
+		var (
+			netconf *types.PluginConf
+			mocked  *utilsMocks.Netlink
+		)
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				Representor:  "dummylink",
+				ActualBridge: "bridge1",
+			}
+			mocked = &utilsMocks.Netlink{}
+		})
+		It("Returns bridge and representor (success)", func() {
+			brMac, _ := net.ParseMAC("6e:16:06:0e:b7:e9")
+			repMac, _ := net.ParseMAC("6e:16:06:0e:b7:ea")
+			mocked.On("LinkByName", netconf.ActualBridge).Return(
+				&netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Name: "bridge1", HardwareAddr: brMac}}, nil)
+			mocked.On("LinkByName", netconf.Representor).Return(
+				&FakeLink{netlink.LinkAttrs{Name: "dummylink", HardwareAddr: repMac}}, nil)
+			m := manager{nLink: mocked}
+			ifaces, err := m.GetHostInterfaces(netconf)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ifaces).To(Equal([]*current.Interface{
+				{Name: "bridge1", Mac: brMac.String()},
+				{Name: "dummylink", Mac: repMac.String()},
+			}))
+			mocked.AssertExpectations(t)
+		})
+		It("Representor not found (failure)", func() {
+			mocked.On("LinkByName", netconf.ActualBridge).Return(&netlink.Bridge{}, nil)
+			mocked.On("LinkByName", netconf.Representor).Return(nil, errors.New("not found"))
+			m := manager{nLink: mocked}
+			_, err := m.GetHostInterfaces(netconf)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+	Context("Checking CheckVF function", func() {
+		var (
+			podifName string
+			netconf   *types.PluginConf
+			result    *current.Result
+			fakeLink  *FakeLink
+			mocked    *utilsMocks.Netlink
+		)
+
+		BeforeEach(func() {
+			podifName = "net1"
+			netconf = &types.PluginConf{
+				NetConf: types.NetConf{
+					DeviceID: "0000:af:06.0",
+				},
+				PFName:      "enp175s0f1",
+				VFID:        0,
+				ContIFNames: "net1",
+				MTU:         2000,
+			}
+			fakeMac, err := net.ParseMAC("6e:16:06:0e:b7:e9")
+			Expect(err).NotTo(HaveOccurred())
+			_, ipNet, err := net.ParseCIDR("192.168.100.101/24")
+			Expect(err).NotTo(HaveOccurred())
+			ipNet.IP = net.ParseIP("192.168.100.101").To4()
+			result = &current.Result{
+				Interfaces: []*current.Interface{{Name: podifName, Mac: fakeMac.String(), Sandbox: "/proc/4123/ns/net"}},
+				IPs:        []*current.IPConfig{{Address: *ipNet, Interface: current.Int(0)}},
+			}
+			fakeLink = &FakeLink{netlink.LinkAttrs{
+				Index:        1000,
+				Name:         podifName,
+				HardwareAddr: fakeMac,
+				MTU:          2000,
+			}}
+			mocked = &utilsMocks.Netlink{}
+			mocked.On("LinkByName", podifName).Return(fakeLink, nil)
+		})
+		It("VF has expected configuration (success)", func() {
+			addr, err := netlink.ParseAddr("192.168.100.101/24")
+			Expect(err).NotTo(HaveOccurred())
+			mocked.On("AddrList", fakeLink, netlink.FAMILY_ALL).Return([]netlink.Addr{*addr}, nil)
+			m := manager{nLink: mocked}
+			Expect(m.CheckVF(netconf, podifName, result, newFakeNs())).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+		})
+		It("VF has unexpected MAC (failure)", func() {
+			result.Interfaces[0].Mac = "6e:16:06:0e:b7:ea"
+			m := manager{nLink: mocked}
+			Expect(m.CheckVF(netconf, podifName, result, newFakeNs())).To(MatchError(ContainSubstring("MAC")))
+		})
+		It("VF has unexpected MTU (failure)", func() {
+			fakeLink.MTU = 1500
+			m := manager{nLink: mocked}
+			Expect(m.CheckVF(netconf, podifName, result, newFakeNs())).To(MatchError(ContainSubstring("MTU")))
+		})
+		It("VF has no IP address configured (failure)", func() {
+			mocked.On("AddrList", fakeLink, netlink.FAMILY_ALL).Return([]netlink.Addr{}, nil)
+			m := manager{nLink: mocked}
+			Expect(m.CheckVF(netconf, podifName, result, newFakeNs())).To(
+				MatchError(ContainSubstring("192.168.100.101/24")))
+		})
+		It("VF not found in the container (failure)", func() {
+			mocked := &utilsMocks.Netlink{}
+			mocked.On("LinkByName", podifName).Return(nil, errors.New("not found"))
+			m := manager{nLink: mocked}
+			Expect(m.CheckVF(netconf, podifName, result, newFakeNs())).To(HaveOccurred())
+		})
+		Context("VF with VLAN sub-interface", func() {
+			var vlanLink *netlink.Vlan
+
+			BeforeEach(func() {
+				netconf.VlanInterfaces = []types.VlanInterface{{ID: 100}}
+				_, ipNet, err := net.ParseCIDR("192.168.200.101/24")
+				Expect(err).NotTo(HaveOccurred())
+				ipNet.IP = net.ParseIP("192.168.200.101").To4()
+				result.Interfaces = append(result.Interfaces,
+					&current.Interface{Name: "net1.100", Mac: result.Interfaces[0].Mac, Sandbox: "/proc/4123/ns/net"})
+				result.IPs = append(result.IPs, &current.IPConfig{Address: *ipNet, Interface: current.Int(1)})
+				vlanLink = &netlink.Vlan{LinkAttrs: netlink.LinkAttrs{Name: "net1.100", ParentIndex: 1000}, VlanId: 100}
+				addr, err := netlink.ParseAddr("192.168.100.101/24")
+				Expect(err).NotTo(HaveOccurred())
+				mocked.On("AddrList", fakeLink, netlink.FAMILY_ALL).Return([]netlink.Addr{*addr}, nil)
+			})
+			It("VLAN sub-interface has expected configuration (success)", func() {
+				addr, err := netlink.ParseAddr("192.168.200.101/24")
+				Expect(err).NotTo(HaveOccurred())
+				mocked.On("LinkByName", "net1.100").Return(vlanLink, nil)
+				mocked.On("AddrList", vlanLink, netlink.FAMILY_ALL).Return([]netlink.Addr{*addr}, nil)
+				m := manager{nLink: mocked}
+				Expect(m.CheckVF(netconf, podifName, result, newFakeNs())).NotTo(HaveOccurred())
+				mocked.AssertExpectations(t)
+			})
+			It("VLAN sub-interface has unexpected VLAN ID (failure)", func() {
+				vlanLink.VlanId = 200
+				mocked.On("LinkByName", "net1.100").Return(vlanLink, nil)
+				m := manager{nLink: mocked}
+				Expect(m.CheckVF(netconf, podifName, result, newFakeNs())).To(MatchError(ContainSubstring("VLAN 100")))
+			})
+			It("VLAN sub-interface has no IP address configured (failure)", func() {
+				mocked.On("LinkByName", "net1.100").Return(vlanLink, nil)
+				mocked.On("AddrList", vlanLink, netlink.FAMILY_ALL).Return([]netlink.Addr{}, nil)
+				m := manager{nLink: mocked}
+				Expect(m.CheckVF(netconf, podifName, result, newFakeNs())).To(
+					MatchError(ContainSubstring("192.168.200.101/24")))
+			})
+		})
+	})
+	Context("Checking CheckStatus function", func() {
+		var (
+			netconf       *types.NetConf
+			fakeBridge    *netlink.Bridge
+			fakeBond      *FakeBondLink
+			fakeUplink    *FakeLink
+			fakeRep       *FakeLink
+			mocked        *utilsMocks.Netlink
+			mockedSr      *utilsMocks.Sriovnet
+			vlanFiltering bool
+		)
+
+		BeforeEach(func() {
+			netconf = &types.NetConf{Vlan: 100}
+			vlanFiltering = true
+			fakeBridge = &netlink.Bridge{
+				LinkAttrs:     netlink.LinkAttrs{Index: 1000, Name: "bridge1"},
+				VlanFiltering: &vlanFiltering,
+			}
+			fakeBond = &FakeBondLink{netlink.LinkAttrs{Index: 20, Name: "bond0", MasterIndex: 1000}}
+			fakeUplink = &FakeLink{netlink.LinkAttrs{Index: 21, Name: "enp175s0f1", MasterIndex: 20}}
+			fakeRep = &FakeLink{netlink.LinkAttrs{Index: 10, Name: "dummylink", MasterIndex: 1000}}
+			mocked = &utilsMocks.Netlink{}
+			mockedSr = &utilsMocks.Sriovnet{}
+			mocked.On("LinkByName", "bridge1").Return(fakeBridge, nil)
+			mocked.On("LinkList").Return([]netlink.Link{fakeBridge, fakeRep, fakeBond, fakeUplink}, nil)
+			mockedSr.On("GetRepresentorPortFlavour", "dummylink").Return(
+				sriovnet.PortFlavour(sriovnet.PORT_FLAVOUR_PCI_VF), nil)
+		})
+		It("Bridge has uplink representor attached through a bond (success)", func() {
+			mockedSr.On("GetRepresentorPortFlavour", "enp175s0f1").Return(
+				sriovnet.PortFlavour(sriovnet.PORT_FLAVOUR_PHYSICAL), nil)
+			m := manager{nLink: mocked, sriov: mockedSr}
+			Expect(m.CheckStatus(netconf, []string{"bridge1"})).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+			mockedSr.AssertExpectations(t)
+		})
+		It("Uplink representor for the VF exists (success)", func() {
+			netconf.DeviceID = "0000:af:06.0"
+			mockedSr.On("GetRepresentorPortFlavour", "enp175s0f1").Return(
+				sriovnet.PortFlavour(sriovnet.PORT_FLAVOUR_PHYSICAL), nil)
+			mockedSr.On("GetUplinkRepresentor", netconf.DeviceID).Return("enp175s0f1", nil)
+			mocked.On("LinkByName", "enp175s0f1").Return(fakeUplink, nil)
+			m := manager{nLink: mocked, sriov: mockedSr}
+			Expect(m.CheckStatus(netconf, []string{"bridge1"})).NotTo(HaveOccurred())
+			mocked.AssertExpectations(t)
+			mockedSr.AssertExpectations(t)
+		})
+		It("Bridge does not exist (failure)", func() {
+			mocked.On("LinkByName", "bridge2").Return(nil, errors.New("not found"))
+			m := manager{nLink: mocked, sriov: mockedSr}
+			Expect(m.CheckStatus(netconf, []string{"bridge2"})).To(MatchError(ContainSubstring("bridge2")))
+		})
+		It("Link is not a bridge (failure)", func() {
+			mocked.On("LinkByName", "dummylink").Return(fakeRep, nil)
+			m := manager{nLink: mocked, sriov: mockedSr}
+			Expect(m.CheckStatus(netconf, []string{"dummylink"})).To(MatchError(ContainSubstring("not a bridge")))
+		})
+		It("Bridge has vlan_filtering disabled (failure)", func() {
+			vlanFiltering = false
+			m := manager{nLink: mocked, sriov: mockedSr}
+			Expect(m.CheckStatus(netconf, []string{"bridge1"})).To(MatchError(ContainSubstring("vlan_filtering")))
+		})
+		It("Bridge has no uplink representor (failure)", func() {
+			mockedSr.On("GetRepresentorPortFlavour", "enp175s0f1").Return(
+				sriovnet.PortFlavour(sriovnet.PORT_FLAVOUR_UNKNOWN), errors.New("not a representor"))
+			m := manager{nLink: mocked, sriov: mockedSr}
+			Expect(m.CheckStatus(netconf, []string{"bridge1"})).To(MatchError(ContainSubstring("no uplink representor")))
+		})
+		It("Uplink representor for the VF is missing (failure)", func() {
+			netconf.DeviceID = "0000:af:06.0"
+			mockedSr.On("GetRepresentorPortFlavour", "enp175s0f1").Return(
+				sriovnet.PortFlavour(sriovnet.PORT_FLAVOUR_PHYSICAL), nil)
+			mockedSr.On("GetUplinkRepresentor", netconf.DeviceID).Return("", errors.New("not found"))
+			m := manager{nLink: mocked, sriov: mockedSr}
+			Expect(m.CheckStatus(netconf, []string{"bridge1"})).To(MatchError(ContainSubstring("uplink representor")))
+		})
+	})
+
+	Context("Checking RecoverState function", func() {
+		var (
+			netconf  *types.PluginConf
+			fakeRep  *FakeLink
+			fakeVF   *FakeLink
+			mocked   *utilsMocks.Netlink
+			mockedSr *utilsMocks.Sriovnet
+		)
+		const podifName = "net1"
+
+		BeforeEach(func() {
+			netconf = &types.PluginConf{
+				NetConf:      types.NetConf{DeviceID: "0000:af:06.0"},
+				PFName:       "enp175s0f1",
+				ActualBridge: "bridge1",
+				VFID:         0,
+				MAC:          "6e:16:06:0e:b7:e9",
+			}
+			fakeMac, err := net.ParseMAC("aa:f3:8d:65:1b:d4")
+			Expect(err).NotTo(HaveOccurred())
+			fakeRep = &FakeLink{netlink.LinkAttrs{Name: "dummylink", Index: 10, MTU: 1500, MasterIndex: 1000}}
+			fakeVF = &FakeLink{netlink.LinkAttrs{Name: podifName, Index: 3, MTU: 9000, HardwareAddr: fakeMac}}
+			mocked = &utilsMocks.Netlink{}
+			mockedSr = &utilsMocks.Sriovnet{}
+			mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return(fakeRep.Name, nil)
+			mocked.On("LinkByName", fakeRep.Name).Return(fakeRep, nil)
+			mocked.On("LinkByName", netconf.ActualBridge).Return(
+				&netlink.Bridge{LinkAttrs: netlink.LinkAttrs{Index: 1000, Name: "bridge1"}}, nil)
+		})
+		It("VF is in the container namespace (success)", func() {
+			mocked.On("LinkByName", podifName).Return(fakeVF, nil)
+			m := manager{nLink: mocked, sriov: mockedSr}
+			Expect(m.RecoverState(netconf, podifName, newFakeNs())).NotTo(HaveOccurred())
+			Expect(netconf.Representor).To(Equal(fakeRep.Name))
+			Expect(netconf.OrigVfState.AdminMAC).To(Equal(defaultVfAdminMAC))
+			Expect(netconf.ContIFNames).To(Equal(podifName))
+			// values which can't be recovered are not set to the current values of the links
+			Expect(netconf.OrigRepState.MTU).To(BeZero())
+			Expect(netconf.OrigVfState.HostIFName).To(BeEmpty())
+			Expect(netconf.OrigVfState.EffectiveMAC).To(BeEmpty())
+			Expect(netconf.OrigVfState.MTU).To(BeZero())
+			mocked.AssertExpectations(t)
+			mockedSr.AssertExpectations(t)
+		})
+		It("VF is not in the container namespace (success)", func() {
+			mocked.On("LinkByName", podifName).Return(nil, errors.New("not found"))
+			m := manager{nLink: mocked, sriov: mockedSr}
+			Expect(m.RecoverState(netconf, podifName, newFakeNs())).NotTo(HaveOccurred())
+			Expect(netconf.Representor).To(Equal(fakeRep.Name))
+			Expect(netconf.ContIFNames).To(BeEmpty())
+		})
+		It("Container namespace doesn't exist (success)", func() {
+			m := manager{nLink: mocked, sriov: mockedSr}
+			Expect(m.RecoverState(netconf, podifName, nil)).NotTo(HaveOccurred())
+			Expect(netconf.ContIFNames).To(BeEmpty())
+		})
+		It("Representor is not attached to the bridge (failure)", func() {
+			fakeRep.MasterIndex = 0
+			m := manager{nLink: mocked, sriov: mockedSr}
+			Expect(m.RecoverState(netconf, podifName, newFakeNs())).To(MatchError(ContainSubstring("not attached")))
+		})
+		It("Representor not found (failure)", func() {
+			mockedSr := &utilsMocks.Sriovnet{}
+			mockedSr.On("GetVfRepresentor", netconf.PFName, netconf.VFID).Return("", errors.New("not found"))
+			m := manager{nLink: mocked, sriov: mockedSr}
+			Expect(m.RecoverState(netconf, podifName, newFakeNs())).To(HaveOccurred())
+		})
+	})
 })
@@ -2,7 +2,11 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	time "time"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // IPCLock is an autogenerated mock type for the IPCLock type
 type IPCLock struct {
@@ -23,6 +27,20 @@ func (_m *IPCLock) Lock() error {
 	return r0
 }
 
+// LockWithTimeout provides a mock function with given fields: timeout
+func (_m *IPCLock) LockWithTimeout(timeout time.Duration) error {
+	ret := _m.Called(timeout)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(time.Duration) error); ok {
+		r0 = rf(timeout)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Unlock provides a mock function with given fields:
 func (_m *IPCLock) Unlock() error {
 	ret := _m.Called()
@@ -3,9 +3,14 @@
 package mocks
 
 import (
+	current "github.com/containernetworking/cni/pkg/types/100"
+	net "net"
+
+	mock "github.com/stretchr/testify/mock"
+
 	ns "github.com/containernetworking/plugins/pkg/ns"
+
 	types "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
-	mock "github.com/stretchr/testify/mock"
 )
 
 // Manager is an autogenerated mock type for the Manager type
@@ -13,6 +18,34 @@ type Manager struct {
 	mock.Mock
 }
 
+// AddBridgeNeighbors provides a mock function with given fields: conf, mac, ips
+func (_m *Manager) AddBridgeNeighbors(conf *types.PluginConf, mac string, ips []net.IP) error {
+	ret := _m.Called(conf, mac, ips)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.PluginConf, string, []net.IP) error); ok {
+		r0 = rf(conf, mac, ips)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AddRepresentorFDB provides a mock function with given fields: conf, mac
+func (_m *Manager) AddRepresentorFDB(conf *types.PluginConf, mac string) error {
+	ret := _m.Called(conf, mac)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.PluginConf, string) error); ok {
+		r0 = rf(conf, mac)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ApplyVFConfig provides a mock function with given fields: conf
 func (_m *Manager) ApplyVFConfig(conf *types.PluginConf) error {
 	ret := _m.Called(conf)
@@ -41,6 +74,48 @@ func (_m *Manager) AttachRepresentor(conf *types.PluginConf) error {
 	return r0
 }
 
+// CheckRepresentor provides a mock function with given fields: conf
+func (_m *Manager) CheckRepresentor(conf *types.PluginConf) error {
+	ret := _m.Called(conf)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.PluginConf) error); ok {
+		r0 = rf(conf)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CheckStatus provides a mock function with given fields: conf, bridges
+func (_m *Manager) CheckStatus(conf *types.NetConf, bridges []string) error {
+	ret := _m.Called(conf, bridges)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.NetConf, []string) error); ok {
+		r0 = rf(conf, bridges)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CheckVF provides a mock function with given fields: conf, podifName, result, netns
+func (_m *Manager) CheckVF(conf *types.PluginConf, podifName string, result *current.Result, netns ns.NetNS) error {
+	ret := _m.Called(conf, podifName, result, netns)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.PluginConf, string, *current.Result, ns.NetNS) error); ok {
+		r0 = rf(conf, podifName, result, netns)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DetachRepresentor provides a mock function with given fields: conf
 func (_m *Manager) DetachRepresentor(conf *types.PluginConf) error {
 	ret := _m.Called(conf)
@@ -55,6 +130,43 @@ func (_m *Manager) DetachRepresentor(conf *types.PluginConf) error {
 	return r0
 }
 
+// GetHostInterfaces provides a mock function with given fields: conf
+func (_m *Manager) GetHostInterfaces(conf *types.PluginConf) ([]*current.Interface, error) {
+	ret := _m.Called(conf)
+
+	var r0 []*current.Interface
+	if rf, ok := ret.Get(0).(func(*types.PluginConf) []*current.Interface); ok {
+		r0 = rf(conf)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*current.Interface)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*types.PluginConf) error); ok {
+		r1 = rf(conf)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecoverState provides a mock function with given fields: conf, podifName, netns
+func (_m *Manager) RecoverState(conf *types.PluginConf, podifName string, netns ns.NetNS) error {
+	ret := _m.Called(conf, podifName, netns)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.PluginConf, string, ns.NetNS) error); ok {
+		r0 = rf(conf, podifName, netns)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // ReleaseVF provides a mock function with given fields: conf, podifName, cid, netns
 func (_m *Manager) ReleaseVF(conf *types.PluginConf, podifName string, cid string, netns ns.NetNS) error {
 	ret := _m.Called(conf, podifName, cid, netns)
@@ -83,6 +195,48 @@ func (_m *Manager) ResetVFConfig(conf *types.PluginConf) error {
 	return r0
 }
 
+// ResolveRepresentor provides a mock function with given fields: conf
+func (_m *Manager) ResolveRepresentor(conf *types.PluginConf) error {
+	ret := _m.Called(conf)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.PluginConf) error); ok {
+		r0 = rf(conf)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RestoreHostVF provides a mock function with given fields: conf
+func (_m *Manager) RestoreHostVF(conf *types.PluginConf) error {
+	ret := _m.Called(conf)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.PluginConf) error); ok {
+		r0 = rf(conf)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveState provides a mock function with given fields: conf
+func (_m *Manager) SaveState(conf *types.PluginConf) error {
+	ret := _m.Called(conf)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*types.PluginConf) error); ok {
+		r0 = rf(conf)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // SetupVF provides a mock function with given fields: conf, podifName, cid, netns
 func (_m *Manager) SetupVF(conf *types.PluginConf, podifName string, cid string, netns ns.NetNS) (string, error) {
 	ret := _m.Called(conf, podifName, cid, netns)
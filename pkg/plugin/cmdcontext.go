@@ -15,6 +15,8 @@ type cmdContext struct {
 
 	netNS  ns.NetNS
 	result *current.Result
+	// index of the container interface in result.Interfaces
+	ifIndex int
 
 	errorHandlers []func()
 }
@@ -2,9 +2,14 @@ package plugin
 
 import "github.com/containernetworking/cni/pkg/types"
 
+// envArgs field names must match CNI_ARGS keys
+//
+//nolint:revive,stylecheck
 type envArgs struct {
 	types.CommonArgs
-	MAC types.UnmarshallableString `json:"mac,omitempty"`
+	MAC               types.UnmarshallableString `json:"mac,omitempty"`
+	K8S_POD_NAME      types.UnmarshallableString
+	K8S_POD_NAMESPACE types.UnmarshallableString
 }
 
 func getEnvArgs(envArgsString string) (*envArgs, error) {
@@ -1,6 +1,9 @@
 package plugin
 
 import (
+	"context"
+
+	"github.com/containernetworking/cni/pkg/invoke"
 	"github.com/containernetworking/cni/pkg/types"
 	current "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/containernetworking/plugins/pkg/ipam"
@@ -9,7 +12,10 @@ import (
 // IPAM represents limited subset of functions from ipam package
 type IPAM interface {
 	ExecAdd(plugin string, netconf []byte) (types.Result, error)
+	ExecCheck(plugin string, netconf []byte) error
 	ExecDel(plugin string, netconf []byte) error
+	ExecStatus(plugin string, netconf []byte) error
+	ExecGC(plugin string, netconf []byte) error
 	ConfigureIface(ifName string, res *current.Result) error
 }
 
@@ -21,11 +27,26 @@ func (i *ipamWrapper) ExecAdd(plugin string, netconf []byte) (types.Result, erro
 	return ipam.ExecAdd(plugin, netconf)
 }
 
+// ExecCheck is a wrapper for ipam.ExecCheck
+func (i *ipamWrapper) ExecCheck(plugin string, netconf []byte) error {
+	return ipam.ExecCheck(plugin, netconf)
+}
+
 // ExecDel is a wrapper for ipam.ExecDel
 func (i *ipamWrapper) ExecDel(plugin string, netconf []byte) error {
 	return ipam.ExecDel(plugin, netconf)
 }
 
+// ExecStatus is a wrapper for invoke.DelegateStatus
+func (i *ipamWrapper) ExecStatus(plugin string, netconf []byte) error {
+	return invoke.DelegateStatus(context.TODO(), plugin, netconf, nil)
+}
+
+// ExecGC is a wrapper for invoke.DelegateGC
+func (i *ipamWrapper) ExecGC(plugin string, netconf []byte) error {
+	return invoke.DelegateGC(context.TODO(), plugin, netconf, nil)
+}
+
 // ConfigureIface is a wrapper for ipam.ConfigureIface
 func (i *ipamWrapper) ConfigureIface(ifName string, res *current.Result) error {
 	return ipam.ConfigureIface(ifName, res)
@@ -0,0 +1,173 @@
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/cache"
+	localtypes "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+)
+
+// CmdAdd steps which change host or VF state and are recorded in the journal
+const (
+	// VF representor attached to the bridge, VLANs added to the representor and the uplink
+	stepAttachRepresentor = "attachRepresentor"
+	// VF administrative MAC and other VF options changed
+	stepApplyVFConfig = "applyVFConfig"
+	// VF moved to the container network namespace, renamed and configured
+	stepSetupVF = "setupVF"
+)
+
+// journalEntry is a persistent record of CmdAdd steps, used to undo changes
+// if the plugin was terminated before CmdAdd completed
+type journalEntry struct {
+	ContainerID string `json:"containerID"`
+	IfName      string `json:"ifName"`
+	Netns       string `json:"netns"`
+	// Steps started by CmdAdd in execution order
+	Steps []string `json:"steps"`
+	// Conf contains state required to undo the steps
+	Conf *localtypes.PluginConf `json:"conf"`
+}
+
+// journal writes journalEntry for the device to the state cache
+type journal struct {
+	cache cache.StateCache
+	ref   cache.StateRef
+	entry journalEntry
+	// undoFailed is set if an undo handler returned by UndoHandler failed
+	undoFailed bool
+}
+
+// newJournal returns journal for the device used by the command
+func (p *Plugin) newJournal(cmdCtx *cmdContext) *journal {
+	return &journal{
+		cache: p.cache,
+		ref:   p.cache.GetJournalRef(cmdCtx.pluginConf.DeviceID),
+		entry: journalEntry{
+			ContainerID: cmdCtx.args.ContainerID,
+			IfName:      cmdCtx.args.IfName,
+			Netns:       cmdCtx.args.Netns,
+			Conf:        cmdCtx.pluginConf,
+		},
+	}
+}
+
+// Begin records the step, should be called before the step is executed
+func (j *journal) Begin(step string) error {
+	j.entry.Steps = append(j.entry.Steps, step)
+	return j.save()
+}
+
+// Update records state changed by the last step, should be called after the step is executed
+func (j *journal) Update() error {
+	return j.save()
+}
+
+// Remove deletes the journal, should be called when all steps are committed or undone
+func (j *journal) Remove() {
+	if len(j.entry.Steps) == 0 {
+		return
+	}
+	if err := j.cache.Delete(j.ref); err != nil {
+		log.Warn().Msgf("failed to remove journal %s: %v", j.ref, err)
+	}
+}
+
+// UndoHandler returns error handler which undoes the step, failure of the undo is recorded
+// so that the journal is kept by RemoveIfUndone
+func (j *journal) UndoHandler(step string, undo func() error) func() {
+	return func() {
+		if err := undo(); err != nil {
+			log.Warn().Msgf("failed to undo step %s for device %s: %v", step, j.entry.Conf.DeviceID, err)
+			j.undoFailed = true
+		}
+	}
+}
+
+// RemoveIfUndone deletes the journal if all steps were undone by the handlers returned by UndoHandler,
+// otherwise the journal is kept and the steps are undone again by the next command for the device
+func (j *journal) RemoveIfUndone() {
+	if j.undoFailed {
+		log.Warn().Msgf("keep journal %s to retry undo", j.ref)
+		return
+	}
+	j.Remove()
+}
+
+func (j *journal) save() error {
+	if err := j.cache.Save(j.ref, &j.entry); err != nil {
+		return fmt.Errorf("failed to write journal: %v", err)
+	}
+	return nil
+}
+
+// recoverJournal undoes steps of the incomplete CmdAdd recorded in the journal for the device,
+// device lock should be held by the caller
+func (p *Plugin) recoverJournal(deviceID string) {
+	if deviceID == "" {
+		return
+	}
+	ref := p.cache.GetJournalRef(deviceID)
+	entry := &journalEntry{}
+	if err := p.cache.Load(ref, entry); err != nil {
+		// no journal for the device
+		return
+	}
+	p.undoJournal(entry)
+	if err := p.cache.Delete(ref); err != nil {
+		log.Warn().Msgf("failed to remove journal %s: %v", ref, err)
+	}
+}
+
+// undoJournal replays undo actions for the steps recorded in the journal entry in reverse order,
+// undo is best-effort: errors are logged and the remaining steps are still undone
+func (p *Plugin) undoJournal(entry *journalEntry) {
+	conf := entry.Conf
+	if conf == nil {
+		return
+	}
+	// CmdAdd may be terminated after the state was cached, but before the journal was removed
+	pRef := p.cache.GetStateRef(conf.Name, entry.ContainerID, entry.IfName)
+	if p.cache.Load(pRef, &localtypes.PluginConf{}) == nil {
+		log.Info().Msgf("journal for device %s belongs to completed attachment %s, skip undo",
+			conf.DeviceID, pRef)
+		return
+	}
+	log.Warn().Msgf("found journal of incomplete CmdAdd for device %s, undoing %v",
+		conf.DeviceID, entry.Steps)
+	for i := len(entry.Steps) - 1; i >= 0; i-- {
+		var err error
+		switch entry.Steps[i] {
+		case stepSetupVF:
+			err = p.undoSetupVF(entry)
+		case stepApplyVFConfig:
+			err = p.manager.ResetVFConfig(conf)
+		case stepAttachRepresentor:
+			err = p.manager.DetachRepresentor(conf)
+		default:
+			err = fmt.Errorf("unknown step")
+		}
+		if err != nil {
+			log.Warn().Msgf("failed to undo step %s for device %s: %v", entry.Steps[i], conf.DeviceID, err)
+		}
+	}
+}
+
+// undoSetupVF returns VF to the host network namespace and restores its original name and configuration
+func (p *Plugin) undoSetupVF(entry *journalEntry) error {
+	conf := entry.Conf
+	// ContIFNames is set only when SetupVF completed
+	conf.ContIFNames = entry.IfName
+	netns, err := p.netNS.GetNS(entry.Netns)
+	if err == nil {
+		defer netns.Close()
+		if err = p.manager.ReleaseVF(conf, entry.IfName, entry.ContainerID, netns); err == nil {
+			return nil
+		}
+		log.Debug().Msgf("failed to release VF from netns %s: %v", entry.Netns, err)
+	}
+	// netns no longer exists or VF was not moved to the netns
+	return p.manager.RestoreHostVF(conf)
+}
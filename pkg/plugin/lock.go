@@ -0,0 +1,65 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/containernetworking/cni/pkg/types"
+
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/manager"
+	localtypes "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+)
+
+const defaultLockTimeout = 30 * time.Second
+
+// deviceLock holds IPC locks for VF related resources, e.g. VF PCI address and representor
+type deviceLock struct {
+	newLock func(name string) manager.IPCLock
+	timeout time.Duration
+	names   map[string]bool
+	locks   []manager.IPCLock
+}
+
+// newDeviceLock returns deviceLock which uses timeout from the network configuration
+func (p *Plugin) newDeviceLock(conf *localtypes.NetConf) *deviceLock {
+	timeout := defaultLockTimeout
+	if conf.LockTimeout > 0 {
+		timeout = time.Duration(conf.LockTimeout) * time.Second
+	}
+	return &deviceLock{
+		newLock: p.newIPCLock,
+		timeout: timeout,
+		names:   make(map[string]bool),
+	}
+}
+
+// Lock acquires locks for the provided names, empty names and names
+// which are already locked are skipped
+func (d *deviceLock) Lock(names ...string) error {
+	for _, name := range names {
+		if name == "" || d.names[name] {
+			continue
+		}
+		lock := d.newLock(name)
+		if err := lock.LockWithTimeout(d.timeout); err != nil {
+			if errors.Is(err, manager.ErrLockTimeout) {
+				return types.NewError(types.ErrTryAgainLater, "device busy",
+					fmt.Sprintf("timed out after %s waiting for lock on %s", d.timeout, name))
+			}
+			return fmt.Errorf("failed to lock %s: %v", name, err)
+		}
+		d.names[name] = true
+		d.locks = append(d.locks, lock)
+	}
+	return nil
+}
+
+// Unlock releases all acquired locks in reverse order
+func (d *deviceLock) Unlock() {
+	for i := len(d.locks) - 1; i >= 0; i-- {
+		_ = d.locks[i].Unlock()
+	}
+	d.locks = nil
+	d.names = make(map[string]bool)
+}
@@ -4,12 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
 	"os"
 	"runtime"
 
 	"github.com/containernetworking/cni/pkg/skel"
 	"github.com/containernetworking/cni/pkg/types"
 	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/cni/pkg/version"
 	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -21,6 +23,17 @@ import (
 	localtypes "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
 )
 
+// errPluginNotAvailable is the CNI error code returned by STATUS
+// when the plugin is not able to serve ADD requests
+const errPluginNotAvailable uint = 50
+
+const (
+	// maximal length of the interface alias (IFALIASZ - 1)
+	maxIfAliasLen = 255
+	// maximal length of the interface alternative name (ALTIFNAMSIZ - 1)
+	maxAltNameLen = 127
+)
+
 //nolint:gochecknoinits
 func init() {
 	// this ensures that main runs only on main thread (thread group leader).
@@ -36,11 +49,12 @@ func setDebugMode() {
 // NewPlugin create and initialize accelerated-bridge-cni Plugin object
 func NewPlugin() *Plugin {
 	return &Plugin{
-		netNS:   &nsWrapper{},
-		ipam:    &ipamWrapper{},
-		manager: manager.NewManager(),
-		config:  config.NewConfig(),
-		cache:   cache.NewStateCache(),
+		netNS:      &nsWrapper{},
+		ipam:       &ipamWrapper{},
+		manager:    manager.NewManager(),
+		config:     config.NewConfig(),
+		cache:      cache.NewStateCache(),
+		newIPCLock: manager.NewDeviceLock,
 	}
 }
 
@@ -51,6 +65,8 @@ type Plugin struct {
 	manager manager.Manager
 	config  config.Loader
 	cache   cache.StateCache
+	// newIPCLock returns IPCLock for the device with provided name
+	newIPCLock func(name string) manager.IPCLock
 }
 
 // CmdAdd implementation of accelerated-bridge-cni plugin
@@ -68,14 +84,31 @@ func (p *Plugin) CmdAdd(args *skel.CmdArgs) error {
 		pluginConf: &localtypes.PluginConf{},
 		result:     &current.Result{},
 	}
+	var devLock *deviceLock
 	defer func() {
 		cmdCtx.handleError(err)
+		// lock should be held until all cleanup steps are done
+		if devLock != nil {
+			devLock.Unlock()
+		}
 	}()
 
 	cmdCtx.registerErrorHandler(func() {
 		log.Error().Msgf("CmdAdd failed - %v.", err)
 	})
 
+	netConf := &localtypes.NetConf{}
+	if err = p.config.LoadConf(args.StdinData, netConf); err != nil {
+		return fmt.Errorf("failed to load netconf: %v", err)
+	}
+
+	devLock = p.newDeviceLock(netConf)
+	if err = devLock.Lock(netConf.DeviceID); err != nil {
+		return err
+	}
+	// undo changes left by the previous CmdAdd for the device which was terminated
+	p.recoverJournal(netConf.DeviceID)
+
 	err = p.config.ParseConf(args.StdinData, cmdCtx.pluginConf)
 	if err != nil {
 		return fmt.Errorf("failed to load netconf: %v", err)
@@ -92,58 +125,40 @@ func (p *Plugin) CmdAdd(args *skel.CmdArgs) error {
 	}
 	defer cmdCtx.netNS.Close()
 
-	cmdCtx.result.Interfaces = []*current.Interface{{
-		Name:    args.IfName,
-		Sandbox: cmdCtx.netNS.Path(),
-	}}
-
-	err = p.getMACAddressConfig(cmdCtx)
-	if err != nil {
-		return fmt.Errorf("failed to get MAC config: %v", err)
-	}
-
-	if err = p.manager.AttachRepresentor(pluginConf); err != nil {
-		return fmt.Errorf("failed to attach representor: %v", err)
+	pRef := p.cache.GetStateRef(pluginConf.Name, args.ContainerID, args.IfName)
+	var cached bool
+	if cached, err = p.printCachedResult(cmdCtx, pRef); cached || err != nil {
+		return err
 	}
-	cmdCtx.registerErrorHandler(func() {
-		_ = p.manager.DetachRepresentor(pluginConf)
-	})
 
-	if err = p.manager.ApplyVFConfig(pluginConf); err != nil {
-		return fmt.Errorf("failed to configure VF %q", err)
+	if pluginConf.PrevResult != nil {
+		// the plugin is chained after other plugins, our interfaces and IPs are appended to the previous result
+		if cmdCtx.result, err = current.NewResultFromResult(pluginConf.PrevResult); err != nil {
+			return fmt.Errorf("failed to convert prevResult: %v", err)
+		}
 	}
 
-	var macAddr string
-	if !pluginConf.IsUserspaceDriver {
-		macAddr, err = p.manager.SetupVF(pluginConf, args.IfName, args.ContainerID, cmdCtx.netNS)
-		cmdCtx.registerErrorHandler(func() {
-			netNSErr := cmdCtx.netNS.Do(func(_ ns.NetNS) error {
-				_, intErr := netlink.LinkByName(args.IfName)
-				return intErr
-			})
-			if netNSErr == nil {
-				_ = p.manager.ReleaseVF(pluginConf, args.IfName, args.ContainerID, cmdCtx.netNS)
-			}
-		})
-
-		if err != nil {
-			return fmt.Errorf("failed to set up pod interface %q from the device %q: %v",
-				args.IfName, pluginConf.PFName, err)
-		}
+	var jrnl *journal
+	if jrnl, err = p.setupDevice(cmdCtx, devLock); err != nil {
+		return err
 	}
 
 	// run the IPAM plugin
 	if pluginConf.IPAM.Type != "" {
-		err = p.configureIPAM(cmdCtx, macAddr)
+		err = p.configureIPAM(cmdCtx)
 		if err != nil {
 			return fmt.Errorf("failed to configure IPAM: %v", err)
 		}
 	}
+	if err = p.configureVlanIPAM(cmdCtx); err != nil {
+		return fmt.Errorf("failed to configure IPAM for VLAN sub-interfaces: %v", err)
+	}
 	// Cache PluginConf for CmdDel
-	pRef := p.cache.GetStateRef(pluginConf.Name, args.ContainerID, args.IfName)
+	pluginConf.Result = cmdCtx.result
 	if err = p.cache.Save(pRef, pluginConf); err != nil {
 		return fmt.Errorf("failed to save PluginConf %q", err)
 	}
+	jrnl.Remove()
 
 	if err = p.updateDeviceInfo(cmdCtx); err != nil {
 		log.Error().Msgf("failed to update DeviceInfo %v.", err)
@@ -152,6 +167,198 @@ func (p *Plugin) CmdAdd(args *skel.CmdArgs) error {
 	return types.PrintResult(cmdCtx.result, pluginConf.CNIVersion)
 }
 
+// printCachedResult prints the cached result if ADD was already completed for the attachment,
+// e.g. runtime retries ADD after a timeout, returns false if the attachment is not cached
+func (p *Plugin) printCachedResult(cmdCtx *cmdContext, pRef cache.StateRef) (bool, error) {
+	cachedConf := &localtypes.PluginConf{}
+	if p.cache.Load(pRef, cachedConf) != nil {
+		return false, nil
+	}
+	if err := p.checkExistingAttachment(cmdCtx, cachedConf); err != nil {
+		return true, fmt.Errorf("attachment already exists and is not valid: %v", err)
+	}
+	log.Info().Msgf("Attachment already exists, returning cached result")
+	return true, types.PrintResult(cachedConf.Result, cmdCtx.pluginConf.CNIVersion)
+}
+
+// setupDevice attaches the representor to the bridge and configures the VF, the steps are recorded
+// in the journal which should be removed by the caller when the attachment is cached
+func (p *Plugin) setupDevice(cmdCtx *cmdContext, devLock *deviceLock) (*journal, error) {
+	pluginConf := cmdCtx.pluginConf
+	if err := p.getMACAddressConfig(cmdCtx); err != nil {
+		return nil, fmt.Errorf("failed to get MAC config: %v", err)
+	}
+	if err := p.getRepresentorTags(cmdCtx); err != nil {
+		return nil, fmt.Errorf("failed to get representor alias: %v", err)
+	}
+
+	if err := p.manager.ResolveRepresentor(pluginConf); err != nil {
+		return nil, err
+	}
+	if err := devLock.Lock(pluginConf.Representor); err != nil {
+		return nil, err
+	}
+	// original state is saved before the first step is recorded,
+	// so that the journal always contains the state required to undo the steps
+	if err := p.manager.SaveState(pluginConf); err != nil {
+		return nil, fmt.Errorf("failed to save original state: %v", err)
+	}
+
+	// journal is removed after all registered error handlers are executed,
+	// it is kept if some step was not undone
+	jrnl := p.newJournal(cmdCtx)
+	cmdCtx.registerErrorHandler(jrnl.RemoveIfUndone)
+
+	if err := jrnl.Begin(stepAttachRepresentor); err != nil {
+		return nil, err
+	}
+	if err := p.manager.AttachRepresentor(pluginConf); err != nil {
+		return nil, fmt.Errorf("failed to attach representor: %v", err)
+	}
+	cmdCtx.registerErrorHandler(jrnl.UndoHandler(stepAttachRepresentor, func() error {
+		return p.manager.DetachRepresentor(pluginConf)
+	}))
+
+	if err := jrnl.Begin(stepApplyVFConfig); err != nil {
+		return nil, err
+	}
+	// VF options applied before ApplyVFConfig failed should be reset too
+	cmdCtx.registerErrorHandler(jrnl.UndoHandler(stepApplyVFConfig, func() error {
+		return p.manager.ResetVFConfig(pluginConf)
+	}))
+	if err := p.manager.ApplyVFConfig(pluginConf); err != nil {
+		return nil, fmt.Errorf("failed to configure VF %q", err)
+	}
+
+	var macAddr string
+	if !pluginConf.IsUserspaceDriver {
+		if err := jrnl.Begin(stepSetupVF); err != nil {
+			return nil, err
+		}
+		var err error
+		if macAddr, err = p.setupVF(cmdCtx, jrnl); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.addResultInterfaces(cmdCtx, macAddr); err != nil {
+		return nil, err
+	}
+
+	// static FDB entries require the effective VF MAC which is known only after SetupVF
+	if pluginConf.StaticFDB {
+		if err := p.addRepresentorFDB(cmdCtx); err != nil {
+			return nil, fmt.Errorf("failed to add static FDB entries: %v", err)
+		}
+	}
+	if err := jrnl.Update(); err != nil {
+		return nil, err
+	}
+	return jrnl, nil
+}
+
+// setupVF moves the VF to the container network namespace and configures it, returns the VF MAC address
+func (p *Plugin) setupVF(cmdCtx *cmdContext, jrnl *journal) (string, error) {
+	args, pluginConf := cmdCtx.args, cmdCtx.pluginConf
+	macAddr, err := p.manager.SetupVF(pluginConf, args.IfName, args.ContainerID, cmdCtx.netNS)
+	cmdCtx.registerErrorHandler(jrnl.UndoHandler(stepSetupVF, func() error {
+		netNSErr := cmdCtx.netNS.Do(func(_ ns.NetNS) error {
+			_, intErr := netlink.LinkByName(args.IfName)
+			return intErr
+		})
+		if netNSErr == nil {
+			return p.manager.ReleaseVF(pluginConf, args.IfName, args.ContainerID, cmdCtx.netNS)
+		}
+		return nil
+	}))
+
+	if err != nil {
+		return "", fmt.Errorf("failed to set up pod interface %q from the device %q: %v",
+			args.IfName, pluginConf.PFName, err)
+	}
+	return macAddr, nil
+}
+
+// addResultInterfaces adds host interfaces, the container interface and its VLAN sub-interfaces to the result
+func (p *Plugin) addResultInterfaces(cmdCtx *cmdContext, macAddr string) error {
+	hostIfaces, err := p.manager.GetHostInterfaces(cmdCtx.pluginConf)
+	if err != nil {
+		return fmt.Errorf("failed to get host interfaces: %v", err)
+	}
+	cmdCtx.result.Interfaces = append(cmdCtx.result.Interfaces, hostIfaces...)
+	cmdCtx.ifIndex = len(cmdCtx.result.Interfaces)
+	cmdCtx.result.Interfaces = append(cmdCtx.result.Interfaces, &current.Interface{
+		Name:    cmdCtx.args.IfName,
+		Mac:     macAddr,
+		Sandbox: cmdCtx.netNS.Path(),
+	})
+	// VLAN sub-interfaces follow the container interface in the result
+	for _, vlanIfName := range cmdCtx.pluginConf.ContVlanIfNames {
+		cmdCtx.result.Interfaces = append(cmdCtx.result.Interfaces, &current.Interface{
+			Name:    vlanIfName,
+			Mac:     macAddr,
+			Sandbox: cmdCtx.netNS.Path(),
+		})
+	}
+	return nil
+}
+
+// addRepresentorFDB adds static FDB entries for the VF MAC to the representor
+func (p *Plugin) addRepresentorFDB(cmdCtx *cmdContext) error {
+	mac, err := getVFMAC(cmdCtx)
+	if err != nil {
+		return err
+	}
+	return p.manager.AddRepresentorFDB(cmdCtx.pluginConf, mac)
+}
+
+// addBridgeNeighbors adds bridge neighbor entries for the container interface addresses allocated by IPAM
+func (p *Plugin) addBridgeNeighbors(cmdCtx *cmdContext) error {
+	mac, err := getVFMAC(cmdCtx)
+	if err != nil {
+		return err
+	}
+	var ips []net.IP
+	for _, ipc := range cmdCtx.result.IPs {
+		// addresses from the previous result belong to other interfaces
+		if ipc.Interface != nil && *ipc.Interface == cmdCtx.ifIndex {
+			ips = append(ips, ipc.Address.IP)
+		}
+	}
+	return p.manager.AddBridgeNeighbors(cmdCtx.pluginConf, mac, ips)
+}
+
+// getVFMAC returns the effective MAC of the VF set by SetupVF,
+// VF with userspace driver uses administrative MAC from the configuration
+func getVFMAC(cmdCtx *cmdContext) (string, error) {
+	mac := cmdCtx.result.Interfaces[cmdCtx.ifIndex].Mac
+	if mac == "" {
+		mac = cmdCtx.pluginConf.MAC
+	}
+	if mac == "" {
+		return "", fmt.Errorf("VF MAC address is not known")
+	}
+	return mac, nil
+}
+
+// checkExistingAttachment validates that attachment created by the previous ADD call
+// is still intact and its cached result can be returned
+func (p *Plugin) checkExistingAttachment(cmdCtx *cmdContext, cachedConf *localtypes.PluginConf) error {
+	if cachedConf.Result == nil {
+		return fmt.Errorf("cached state has no result")
+	}
+	if cachedConf.DeviceID != cmdCtx.pluginConf.DeviceID {
+		return fmt.Errorf("attachment uses another device %s", cachedConf.DeviceID)
+	}
+	if err := p.manager.CheckRepresentor(cachedConf); err != nil {
+		return err
+	}
+	if cachedConf.IsUserspaceDriver {
+		return nil
+	}
+	return p.manager.CheckVF(cachedConf, cmdCtx.args.IfName, cachedConf.Result, cmdCtx.netNS)
+}
+
 // updateDeviceInfo updates CNIDeviceInfoFile file with information
 // about VF representor
 func (p *Plugin) updateDeviceInfo(cmdCtx *cmdContext) error {
@@ -254,21 +461,84 @@ func (p *Plugin) getMACAddressConfig(cmdCtx *cmdContext) error {
 	return nil
 }
 
-// call ipam plugin
-func (p *Plugin) configureIPAM(cmdCtx *cmdContext, macAddr string) error {
+// representor alias and alternative name identify the pod which uses the VF,
+// pod name and namespace are taken from CNI_ARGS if provided by the runtime
+func (p *Plugin) getRepresentorTags(cmdCtx *cmdContext) error {
+	envArgs, err := getEnvArgs(cmdCtx.args.Args)
+	if err != nil {
+		return fmt.Errorf("failed to parse args: %v", err)
+	}
+	var podName, podNamespace string
+	if envArgs != nil {
+		podName = string(envArgs.K8S_POD_NAME)
+		podNamespace = string(envArgs.K8S_POD_NAMESPACE)
+	}
+
+	pluginConf := cmdCtx.pluginConf
+	alias := fmt.Sprintf("accelerated-bridge: network=%s container=%s", pluginConf.Name, cmdCtx.args.ContainerID)
+	if podName != "" {
+		alias += fmt.Sprintf(" pod=%s/%s", podNamespace, podName)
+	}
+	pluginConf.RepAlias = truncate(alias, maxIfAliasLen)
+
+	if !pluginConf.RepresentorAltName {
+		return nil
+	}
+	if podName == "" || podNamespace == "" {
+		log.Warn().Msgf("pod name or namespace is not provided in CNI_ARGS, skip representor altname")
+		return nil
+	}
+	// "_" is not allowed in kubernetes object names, so the altname is not ambiguous
+	pluginConf.RepAltName = truncate(
+		fmt.Sprintf("%s_%s_%s", podNamespace, podName, cmdCtx.args.IfName), maxAltNameLen)
+	return nil
+}
+
+// truncate returns s truncated to maxLen bytes
+func truncate(s string, maxLen int) string {
+	if len(s) > maxLen {
+		return s[:maxLen]
+	}
+	return s
+}
+
+// call ipam plugin and add IPAM result to the command result,
+// allocated addresses are added to the bridge neighbor table if ARP/ND suppression is enabled
+func (p *Plugin) configureIPAM(cmdCtx *cmdContext) error {
+	err := p.execIPAMAdd(cmdCtx, cmdCtx.pluginConf.IPAM.Type, cmdCtx.args.StdinData,
+		cmdCtx.args.IfName, cmdCtx.ifIndex)
+	if err != nil {
+		return err
+	}
+	if cmdCtx.pluginConf.NeighSuppress {
+		if err = p.addBridgeNeighbors(cmdCtx); err != nil {
+			return fmt.Errorf("failed to add bridge neighbor entries: %v", err)
+		}
+	}
+	return nil
+}
+
+// execIPAMAdd runs the IPAM plugin for the container interface with provided name and index in the result,
+// configures addresses and routes on the interface and appends them to the result
+func (p *Plugin) execIPAMAdd(cmdCtx *cmdContext, ipamType string, stdin []byte, ifName string, ifIndex int) error {
 	var ipamResult types.Result
 	var err error
 
 	pluginConf := cmdCtx.pluginConf
-	args := cmdCtx.args
 
-	if ipamResult, err = p.ipam.ExecAdd(pluginConf.IPAM.Type, args.StdinData); err != nil {
+	err = withIfName(ifName, func() error {
+		ipamResult, err = p.ipam.ExecAdd(ipamType, stdin)
+		return err
+	})
+	if err != nil {
 		return fmt.Errorf("failed to set up IPAM plugin type %q from the device %q: %v",
-			pluginConf.IPAM.Type, pluginConf.PFName, err)
+			ipamType, pluginConf.PFName, err)
 	}
 
 	cmdCtx.registerErrorHandler(func() {
-		_ = p.ipam.ExecDel(pluginConf.IPAM.Type, args.StdinData)
+		_ = withIfName(ifName, func() error {
+			return p.ipam.ExecDel(ipamType, stdin)
+		})
 	})
 
 	// Convert the IPAM result into the current Result type
@@ -282,34 +552,36 @@ func (p *Plugin) configureIPAM(cmdCtx *cmdContext, macAddr string) error {
 		return err
 	}
 
-	newResult.Interfaces = cmdCtx.result.Interfaces
-	newResult.Interfaces[0].Mac = macAddr
-
 	for _, ipc := range newResult.IPs {
 		// All addresses apply to the container interface (move from host)
-		ipc.Interface = current.Int(0)
+		ipc.Interface = current.Int(ifIndex)
 	}
 
+	result := cmdCtx.result
 	if !pluginConf.IsUserspaceDriver {
+		// addresses and routes from the previous result belong to other plugins
+		ifaceResult := &current.Result{
+			Interfaces: result.Interfaces,
+			IPs:        newResult.IPs,
+			Routes:     newResult.Routes,
+		}
 		err = cmdCtx.netNS.Do(func(_ ns.NetNS) error {
-			return p.ipam.ConfigureIface(args.IfName, newResult)
+			return p.ipam.ConfigureIface(ifName, ifaceResult)
 		})
 		if err != nil {
 			return err
 		}
 	}
-	cmdCtx.result = newResult
+	result.IPs = append(result.IPs, newResult.IPs...)
+	result.Routes = append(result.Routes, newResult.Routes...)
+	if result.DNS.IsEmpty() {
+		result.DNS = newResult.DNS
+	}
 	return nil
 }
 
 // CmdDel implementation of accelerated-bridge-cni plugin
 func (p *Plugin) CmdDel(args *skel.CmdArgs) error {
-	// https://github.com/kubernetes/kubernetes/pull/35240
-	if args.Netns == "" {
-		log.Warn().Msgf("CmdDel skipping - netns is not provided.")
-		return nil
-	}
-
 	var err error
 	defer func() {
 		if err == nil {
@@ -325,75 +597,437 @@ func (p *Plugin) CmdDel(args *skel.CmdArgs) error {
 		return err
 	}
 
+	devLock := p.newDeviceLock(netConf)
+	if err = devLock.Lock(netConf.DeviceID); err != nil {
+		return err
+	}
+	defer devLock.Unlock()
+	// undo changes left by the previous CmdAdd for the device which was terminated
+	p.recoverJournal(netConf.DeviceID)
+
 	pRef := p.cache.GetStateRef(netConf.Name, args.ContainerID, args.IfName)
 
 	pluginConf := &localtypes.PluginConf{}
-	err = p.cache.Load(pRef, pluginConf)
-	if err != nil {
-		// If cmdDel() fails, cached netconf is cleaned up by
-		// the followed defer call or might not exist in the first place.
-		// However, subsequence calls of cmdDel()
-		// from container runtime fail in a dead loop because
-		// the cached netconf doesn't exist.
-		// Return nil when cache.Load() fails since the rest
-		// of cmdDel() code relies on netconf as input argument
-		// and there is no meaning to continue.
-		return nil
+	isCached := true
+	if loadErr := p.cache.Load(pRef, pluginConf); loadErr != nil {
+		log.Warn().Msgf("failed to load cached state: %v, trying to recover it", loadErr)
+		isCached = false
+		// runtime may call DEL again after the VF was released and reused by another attachment
+		if used, usedErr := p.isDeviceUsed(netConf.DeviceID, map[cache.StateRef]bool{pRef: true}); used || usedErr != nil {
+			log.Warn().Msgf("VF %s may be used by another attachment, skip state recovery: %v",
+				netConf.DeviceID, usedErr)
+			return nil
+		}
+		pluginConf, err = p.recoverPluginConf(args)
+		if err != nil {
+			// If cmdDel() fails, cached netconf is cleaned up by
+			// the followed defer call or might not exist in the first place.
+			// However, subsequence calls of cmdDel()
+			// from container runtime fail in a dead loop because
+			// the cached netconf doesn't exist.
+			// Return nil when state can't be recovered since the rest
+			// of cmdDel() code relies on netconf as input argument
+			// and there is no meaning to continue.
+			log.Warn().Msgf("failed to recover state: %v", err)
+			err = nil
+			return nil
+		}
 	}
 
 	if pluginConf.Debug {
 		setDebugMode()
 	}
 
+	if err = devLock.Lock(pluginConf.DeviceID, pluginConf.Representor); err != nil {
+		return err
+	}
+
 	defer func() {
-		if err == nil {
+		if err == nil && isCached {
 			_ = p.cache.Delete(pRef)
 		}
 	}()
 
-	if err = p.manager.DetachRepresentor(pluginConf); err != nil {
-		log.Warn().Msgf("failed to detach representor: %v", err)
+	// netns independent cleanup should be done even if netns or IPAM cleanup fails,
+	// errors are collected and returned after all steps are done
+	var errs []error
+	if detachErr := p.manager.DetachRepresentor(pluginConf); detachErr != nil {
+		log.Warn().Msgf("failed to detach representor: %v", detachErr)
+	}
+
+	if ipamErr := p.execVlanIPAM(pluginConf.VlanInterfaces, args.IfName, args.StdinData,
+		p.ipam.ExecDel); ipamErr != nil {
+		errs = append(errs, ipamErr)
 	}
 
 	if pluginConf.IPAM.Type != "" {
-		err = p.ipam.ExecDel(pluginConf.IPAM.Type, args.StdinData)
-		if err != nil {
-			return err
+		if ipamErr := p.ipam.ExecDel(pluginConf.IPAM.Type, args.StdinData); ipamErr != nil {
+			errs = append(errs, ipamErr)
 		}
 	}
 
+	// netns dependent cleanup
+	if releaseErr := p.releaseVF(pluginConf, args); releaseErr != nil {
+		errs = append(errs, releaseErr)
+	}
+
+	//nolint:gocritic
+	if resetErr := p.manager.ResetVFConfig(pluginConf); resetErr != nil {
+		errs = append(errs, fmt.Errorf("cmdDel() error reseting VF: %q", resetErr))
+	}
+
+	err = errors.Join(errs...)
+	return err
+}
+
+// releaseVF moves VF from the container network namespace to the host network namespace
+// and restores VF's original name and configuration
+func (p *Plugin) releaseVF(pluginConf *localtypes.PluginConf, args *skel.CmdArgs) error {
+	if pluginConf.IsUserspaceDriver || pluginConf.ContIFNames == "" {
+		return nil
+	}
+
+	// netns is not provided if the runtime lost it, e.g. after kubelet restart:
+	// https://github.com/kubernetes/kubernetes/pull/35240
+	if args.Netns == "" {
+		log.Warn().Msgf("netns is not provided, restoring VF in host namespace")
+		if err := p.manager.RestoreHostVF(pluginConf); err != nil {
+			log.Warn().Msgf("failed to restore VF in host namespace: %v", err)
+		}
+		return nil
+	}
+
 	netns, err := p.netNS.GetNS(args.Netns)
 	if err != nil {
 		// according to:
 		// https://github.com/kubernetes/kubernetes/issues/43014#issuecomment-287164444
 		// if provided path does not exist (e.x. when node was restarted)
 		// plugin should silently return with success after releasing
-		// IPAM resources
-		_, ok := err.(ns.NSPathNotExistErr)
-		if ok {
+		// IPAM resources.
+		// VF is returned to the host network namespace by the kernel
+		// when the container network namespace is destroyed,
+		// restore VF configuration in the host network namespace
+		if _, ok := err.(ns.NSPathNotExistErr); ok {
+			if err = p.manager.RestoreHostVF(pluginConf); err != nil {
+				log.Warn().Msgf("failed to restore VF in host namespace: %v", err)
+			}
 			return nil
 		}
 
-		return fmt.Errorf("failed to open netns %s: %q", netns, err)
+		return fmt.Errorf("failed to open netns %s: %q", args.Netns, err)
 	}
 	defer netns.Close()
 
-	if !pluginConf.IsUserspaceDriver {
-		//nolint:gocritic
-		if err = p.manager.ReleaseVF(pluginConf, args.IfName, args.ContainerID, netns); err != nil {
-			return err
+	return p.manager.ReleaseVF(pluginConf, args.IfName, args.ContainerID, netns)
+}
+
+// isDeviceUsed returns true if the VF is referenced by a cached attachment of any network except the excluded ones,
+// device lock should be held by the caller
+func (p *Plugin) isDeviceUsed(deviceID string, exclude map[cache.StateRef]bool) (bool, error) {
+	refs, err := p.cache.List()
+	if err != nil {
+		return false, err
+	}
+	for _, ref := range refs {
+		if exclude[ref] {
+			continue
+		}
+		conf := &localtypes.PluginConf{}
+		if loadErr := p.cache.Load(ref, conf); loadErr != nil {
+			log.Warn().Msgf("failed to load cached state %s: %v", ref, loadErr)
+			continue
+		}
+		if conf.DeviceID == deviceID {
+			return true, nil
 		}
 	}
+	return false, nil
+}
 
-	//nolint:gocritic
-	if err = p.manager.ResetVFConfig(pluginConf); err != nil {
-		return fmt.Errorf("cmdDel() error reseting VF: %q", err)
+// recoverPluginConf reconstructs PluginConf from the network configuration and
+// the current state of the VF, used when cached state for the attachment is missing
+func (p *Plugin) recoverPluginConf(args *skel.CmdArgs) (*localtypes.PluginConf, error) {
+	cmdCtx := &cmdContext{
+		args:       args,
+		pluginConf: &localtypes.PluginConf{},
+	}
+	if err := p.config.ReconstructConf(args.StdinData, cmdCtx.pluginConf); err != nil {
+		return nil, fmt.Errorf("failed to reconstruct netconf: %v", err)
+	}
+	if err := p.getMACAddressConfig(cmdCtx); err != nil {
+		return nil, fmt.Errorf("failed to get MAC config: %v", err)
 	}
 
-	return nil
+	netns, err := p.netNS.GetNS(args.Netns)
+	if err != nil {
+		log.Warn().Msgf("failed to open netns %q: %v", args.Netns, err)
+		netns = nil
+	} else {
+		defer netns.Close()
+	}
+
+	if err = p.manager.RecoverState(cmdCtx.pluginConf, args.IfName, netns); err != nil {
+		return nil, fmt.Errorf("failed to recover state: %v", err)
+	}
+	return cmdCtx.pluginConf, nil
 }
 
 // CmdCheck implementation of accelerated-bridge-cni plugin
 func (p *Plugin) CmdCheck(args *skel.CmdArgs) error {
+	var err error
+	defer func() {
+		if err == nil {
+			log.Debug().Msg("CmdCheck done.")
+		} else {
+			log.Error().Msgf("CmdCheck failed - %v.", err)
+		}
+	}()
+
+	netConf := &localtypes.NetConf{}
+	if err = p.config.LoadConf(args.StdinData, netConf); err != nil {
+		err = types.NewError(types.ErrInvalidNetworkConfig, "failed to load netconf", err.Error())
+		return err
+	}
+
+	if netConf.RawPrevResult == nil {
+		err = types.NewError(types.ErrInvalidNetworkConfig, "required prevResult is missing", "")
+		return err
+	}
+	if err = version.ParsePrevResult(&netConf.NetConf); err != nil {
+		err = types.NewError(types.ErrDecodingFailure, "failed to parse prevResult", err.Error())
+		return err
+	}
+	result, err := current.NewResultFromResult(netConf.PrevResult)
+	if err != nil {
+		err = types.NewError(types.ErrDecodingFailure, "failed to convert prevResult", err.Error())
+		return err
+	}
+
+	pRef := p.cache.GetStateRef(netConf.Name, args.ContainerID, args.IfName)
+
+	pluginConf := &localtypes.PluginConf{}
+	if err = p.cache.Load(pRef, pluginConf); err != nil {
+		err = types.NewError(types.ErrInternal, "failed to load cached state", err.Error())
+		return err
+	}
+
+	if pluginConf.Debug {
+		setDebugMode()
+	}
+
+	if pluginConf.IPAM.Type != "" {
+		if err = p.ipam.ExecCheck(pluginConf.IPAM.Type, args.StdinData); err != nil {
+			return err
+		}
+	}
+	err = p.execVlanIPAM(pluginConf.VlanInterfaces, args.IfName, args.StdinData, p.ipam.ExecCheck)
+	if err != nil {
+		return err
+	}
+
+	if err = p.manager.CheckRepresentor(pluginConf); err != nil {
+		err = checkError("representor check failed", err)
+		return err
+	}
+
+	if pluginConf.IsUserspaceDriver {
+		return nil
+	}
+
+	netns, err := p.netNS.GetNS(args.Netns)
+	if err != nil {
+		err = types.NewError(types.ErrInternal, "failed to open netns", fmt.Sprintf("%s: %v", args.Netns, err))
+		return err
+	}
+	defer netns.Close()
+
+	if err = p.manager.CheckVF(pluginConf, args.IfName, result, netns); err != nil {
+		err = checkError("VF check failed", err)
+	}
+	return err
+}
+
+// checkError converts error of the attachment check to CNI error, attachment which doesn't match
+// its configuration is reported as invalid network config, other errors are internal
+func checkError(msg string, err error) error {
+	code := types.ErrInternal
+	if errors.Is(err, manager.ErrStateMismatch) {
+		code = types.ErrInvalidNetworkConfig
+	}
+	return types.NewError(code, msg, err.Error())
+}
+
+// CmdStatus implementation of accelerated-bridge-cni plugin
+func (p *Plugin) CmdStatus(args *skel.CmdArgs) error {
+	var err error
+	defer func() {
+		if err == nil {
+			log.Debug().Msg("CmdStatus done.")
+		} else {
+			log.Error().Msgf("CmdStatus failed - %v.", err)
+		}
+	}()
+
+	netConf := &localtypes.NetConf{}
+	if err = p.config.LoadConf(args.StdinData, netConf); err != nil {
+		return types.NewError(types.ErrInvalidNetworkConfig, "failed to load netconf", err.Error())
+	}
+
+	if netConf.Debug {
+		setDebugMode()
+	}
+
+	bridges, err := config.GetBridgeNames(netConf.Bridge)
+	if err != nil {
+		return types.NewError(types.ErrInvalidNetworkConfig, "invalid bridge configuration", err.Error())
+	}
+
+	if err = p.manager.CheckStatus(netConf, bridges); err != nil {
+		return types.NewError(errPluginNotAvailable, "plugin is not available", err.Error())
+	}
+
+	if netConf.IPAM.Type != "" {
+		if err = p.ipam.ExecStatus(netConf.IPAM.Type, args.StdinData); err != nil {
+			return err
+		}
+	}
+	err = p.execVlanIPAM(netConf.VlanInterfaces, "", args.StdinData, p.ipam.ExecStatus)
+	return err
+}
+
+// CmdGC implementation of accelerated-bridge-cni plugin
+func (p *Plugin) CmdGC(args *skel.CmdArgs) error {
+	var err error
+	defer func() {
+		if err == nil {
+			log.Debug().Msg("CmdGC done.")
+		} else {
+			log.Error().Msgf("CmdGC failed - %v.", err)
+		}
+	}()
+
+	netConf := &localtypes.NetConf{}
+	if err = p.config.LoadConf(args.StdinData, netConf); err != nil {
+		return err
+	}
+
+	if netConf.Debug {
+		setDebugMode()
+	}
+
+	validRefs := make(map[cache.StateRef]bool, len(netConf.ValidAttachments))
+	for _, attachment := range netConf.ValidAttachments {
+		validRefs[p.cache.GetStateRef(netConf.Name, attachment.ContainerID, attachment.IfName)] = true
+	}
+
+	refs, err := p.cache.List()
+	if err != nil {
+		return err
+	}
+
+	staleConfs := make(map[cache.StateRef]*localtypes.PluginConf)
+	staleRefs := make(map[cache.StateRef]bool)
+	for _, ref := range refs {
+		pluginConf := &localtypes.PluginConf{}
+		if loadErr := p.cache.Load(ref, pluginConf); loadErr != nil {
+			log.Warn().Msgf("failed to load cached state %s: %v", ref, loadErr)
+			continue
+		}
+		if pluginConf.Name == netConf.Name && !validRefs[ref] {
+			staleConfs[ref] = pluginConf
+			staleRefs[ref] = true
+		}
+	}
+
+	var errs []error
+	// host resources of the VF referenced by several stale attachments are released only once
+	releasedDevices := make(map[string]bool)
+	for _, ref := range refs {
+		pluginConf, ok := staleConfs[ref]
+		if !ok {
+			continue
+		}
+		gcErr := p.releaseStaleAttachment(ref, pluginConf, staleRefs, releasedDevices[pluginConf.DeviceID])
+		if gcErr != nil {
+			errs = append(errs, gcErr)
+		}
+		releasedDevices[pluginConf.DeviceID] = true
+	}
+
+	if journalErr := p.recoverNetworkJournals(netConf.Name); journalErr != nil {
+		errs = append(errs, journalErr)
+	}
+
+	if netConf.IPAM.Type != "" {
+		if ipamErr := p.ipam.ExecGC(netConf.IPAM.Type, args.StdinData); ipamErr != nil {
+			errs = append(errs, ipamErr)
+		}
+	}
+	if len(netConf.VlanInterfaces) > 0 {
+		if ipamErr := p.execVlanIPAMGC(netConf.VlanInterfaces, args.StdinData); ipamErr != nil {
+			errs = append(errs, ipamErr)
+		}
+	}
+
+	err = errors.Join(errs...)
+	return err
+}
+
+// releaseStaleAttachment releases host resources of the attachment which no longer exists
+// and removes its cached state. Host resources are not touched if they were already released
+// or if the VF is referenced by a cached attachment which is not stale, e.g. the VF was reused by another Pod
+func (p *Plugin) releaseStaleAttachment(ref cache.StateRef, conf *localtypes.PluginConf,
+	staleRefs map[cache.StateRef]bool, released bool) error {
+	log.Info().Msgf("Releasing stale attachment %s", ref)
+	devLock := p.newDeviceLock(&conf.NetConf)
+	if err := devLock.Lock(conf.DeviceID, conf.Representor); err != nil {
+		return err
+	}
+	defer devLock.Unlock()
+
+	// cache is checked under the lock, concurrent DEL may release the attachment
+	// and ADD for the VF may complete after the stale attachments were listed
+	if loadErr := p.cache.Load(ref, &localtypes.PluginConf{}); loadErr != nil {
+		log.Info().Msgf("stale attachment %s was already released: %v", ref, loadErr)
+		return nil
+	}
+	deviceInUse, err := p.isDeviceUsed(conf.DeviceID, staleRefs)
+	if err != nil {
+		return fmt.Errorf("failed to check if VF of stale attachment %s is in use: %v", ref, err)
+	}
+	if !released && !deviceInUse {
+		if err := p.manager.DetachRepresentor(conf); err != nil {
+			log.Warn().Msgf("failed to detach representor: %v", err)
+		}
+		if err := p.manager.ResetVFConfig(conf); err != nil {
+			return fmt.Errorf("failed to reset VF config for stale attachment %s: %v", ref, err)
+		}
+	}
+	return p.cache.Delete(ref)
+}
+
+// recoverNetworkJournals undoes changes left by terminated CmdAdd calls for the network
+func (p *Plugin) recoverNetworkJournals(networkName string) error {
+	refs, err := p.cache.ListJournals()
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		entry := &journalEntry{}
+		if loadErr := p.cache.Load(ref, entry); loadErr != nil {
+			log.Warn().Msgf("failed to load journal %s: %v", ref, loadErr)
+			continue
+		}
+		if entry.Conf == nil || entry.Conf.Name != networkName {
+			continue
+		}
+		// CmdAdd which owns the journal may still be in progress, wait for it
+		devLock := p.newDeviceLock(&entry.Conf.NetConf)
+		if err = devLock.Lock(entry.Conf.DeviceID); err != nil {
+			return err
+		}
+		p.recoverJournal(entry.Conf.DeviceID)
+		devLock.Unlock()
+	}
 	return nil
 }
@@ -1,6 +1,7 @@
 package plugin
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
@@ -15,6 +16,7 @@ import (
 	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/cache"
 	cacheMocks "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/cache/mocks"
 	configMocks "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/config/mocks"
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/manager"
 	managerMocks "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/manager/mocks"
 	pluginMocks "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/plugin/mocks"
 	localtypes "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
@@ -47,6 +49,8 @@ var (
 	testValidMAC2                       = "b3:ec:90:4c:5b:12"
 	testValidMAC3                       = "b3:ec:90:4c:5b:13"
 	testValidCacheRef    cache.StateRef = "/var/lib/cni/accelerated-bridge/mynet-a1b2c3d4e5f6-net1"
+	testValidJournalRef  cache.StateRef = "journal/0000:af:06.1"
+	testValidRepAlias                   = "accelerated-bridge: network=mynet container=a1b2c3d4e5f6"
 	errTest                             = errors.New("test err")
 )
 
@@ -69,6 +73,13 @@ func getValidPluginConf() *localtypes.PluginConf {
 	}
 }
 
+func getValidHostInterfaces() []*current.Interface {
+	return []*current.Interface{
+		{Name: testValidBridge, Mac: testValidMAC2},
+		{Name: testValidRepName, Mac: testValidMAC3},
+	}
+}
+
 func getValidCmdArgs() *skel.CmdArgs {
 	return &skel.CmdArgs{
 		Netns:       testValidNSPath,
@@ -90,6 +101,18 @@ func getValidIPAMResult() types.Result {
 	}
 }
 
+func getValidPrevResult() map[string]interface{} {
+	return map[string]interface{}{
+		"cniVersion": "0.4.0",
+		"interfaces": []interface{}{
+			map[string]interface{}{"name": testValidContIFNames, "mac": testValidMAC, "sandbox": testValidNSPath},
+		},
+		"ips": []interface{}{
+			map[string]interface{}{"address": "192.168.100.101/24", "interface": 0},
+		},
+	}
+}
+
 var _ = Describe("Plugin - test CNI command flows", func() {
 	var (
 		t           GinkgoTInterface
@@ -100,6 +123,7 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 		managerMock *managerMocks.Manager
 		configMock  *configMocks.Loader
 		netNSMock   *pluginMocks.NetNS
+		lockMock    *managerMocks.IPCLock
 		pluginConf  *localtypes.PluginConf
 		cmdArgs     *skel.CmdArgs
 	)
@@ -112,12 +136,18 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 		managerMock = &managerMocks.Manager{}
 		configMock = &configMocks.Loader{}
 		netNSMock = &pluginMocks.NetNS{}
+		lockMock = &managerMocks.IPCLock{}
+		lockMock.On("LockWithTimeout", defaultLockTimeout).Return(nil).Maybe()
+		lockMock.On("Unlock").Return(nil).Maybe()
 		plugin = Plugin{
 			netNS:   nsMock,
 			ipam:    ipamMock,
 			manager: managerMock,
 			config:  configMock,
 			cache:   cacheMock,
+			newIPCLock: func(_ string) manager.IPCLock {
+				return lockMock
+			},
 		}
 		pluginConf = getValidPluginConf()
 		cmdArgs = getValidCmdArgs()
@@ -130,12 +160,35 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 		managerMock.AssertExpectations(t)
 		configMock.AssertExpectations(t)
 		netNSMock.AssertExpectations(t)
+		lockMock.AssertExpectations(t)
 	})
 
 	Describe("CmdAdd", func() {
+		JustBeforeEach(func() {
+			pluginConf.RepAlias = testValidRepAlias
+		})
+		successfullyLoadConfig := func() {
+			configMock.On("LoadConf", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				*args[1].(*localtypes.NetConf) = pluginConf.NetConf
+			}).Return(nil).Once()
+		}
+		noJournal := func() {
+			cacheMock.On("GetJournalRef", testValidDeviceID).Return(testValidJournalRef).Once()
+			cacheMock.On("Load", testValidJournalRef, mock.Anything).Return(errTest).Once()
+		}
+		journalStarted := func() {
+			cacheMock.On("GetJournalRef", testValidDeviceID).Return(testValidJournalRef).Once()
+			cacheMock.On("Save", testValidJournalRef, mock.Anything).Return(nil)
+			cacheMock.On("Delete", testValidJournalRef).Return(nil).Once()
+		}
 		successfullyParseConfig := func(_ bool) {
+			successfullyLoadConfig()
+			noJournal()
 			configMock.On("ParseConf", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
-				*args[1].(*localtypes.PluginConf) = *pluginConf
+				conf := args[1].(*localtypes.PluginConf)
+				*conf = *pluginConf
+				// representor tags are set by CmdAdd
+				conf.RepAlias, conf.RepAltName = "", ""
 			}).Return(nil).Once()
 		}
 		successfullyGetNS := func(withDeps bool) {
@@ -143,12 +196,22 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 				successfullyParseConfig(true)
 			}
 			nsMock.On("GetNS", testValidNSPath).Return(netNSMock, nil).Once()
-			netNSMock.On("Path").Return(testValidNSPath).Once()
+			cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
+				Return(testValidCacheRef).Once()
+			cacheMock.On("Load", testValidCacheRef, mock.Anything).Return(errTest).Once()
 		}
-		successfullyAttachRepresentor := func(withDeps bool) {
+		successfullySaveState := func(withDeps bool) {
 			if withDeps {
 				successfullyGetNS(true)
 			}
+			managerMock.On("ResolveRepresentor", pluginConf).Return(nil).Once()
+			managerMock.On("SaveState", pluginConf).Return(nil).Once()
+		}
+		successfullyAttachRepresentor := func(withDeps bool) {
+			if withDeps {
+				successfullySaveState(true)
+			}
+			journalStarted()
 			managerMock.On("AttachRepresentor", pluginConf).Return(nil).Once()
 		}
 		successfullyApplyVFConfig := func(withDeps bool) {
@@ -157,6 +220,10 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 			}
 			managerMock.On("ApplyVFConfig", pluginConf).Return(nil).Once()
 		}
+		successfullyGetHostInterfaces := func() {
+			managerMock.On("GetHostInterfaces", pluginConf).Return(getValidHostInterfaces(), nil).Once()
+			netNSMock.On("Path").Return(testValidNSPath).Once()
+		}
 		successfullySetupVF := func(withDeps bool) {
 			if withDeps {
 				successfullyApplyVFConfig(true)
@@ -164,6 +231,7 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 			managerMock.On("SetupVF",
 				pluginConf, testValidContIFNames, testValidContainerID, netNSMock).
 				Return(testValidMAC, nil).Once()
+			successfullyGetHostInterfaces()
 		}
 		successfullyExecAdd := func(withDeps bool) {
 			if withDeps {
@@ -178,17 +246,21 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 			}
 			ipamMock.On("ConfigureIface", cmdArgs.IfName,
 				mock.MatchedBy(func(conf *current.Result) bool {
-					return len(conf.Interfaces) > 0 && conf.Interfaces[0].Mac == testValidMAC
+					return len(conf.Interfaces) == 3 && conf.Interfaces[2].Mac == testValidMAC &&
+						len(conf.IPs) == 1 && *conf.IPs[0].Interface == 2
 				})).
 				Return(nil).Once()
 			netNSMock.On("Do", mock.Anything).Return(func(f func(ns.NetNS) error) error {
 				return f(nil)
 			}).Once()
 		}
+		savedConf := func() interface{} {
+			return mock.MatchedBy(func(conf *localtypes.PluginConf) bool {
+				return conf.DeviceID == pluginConf.DeviceID && conf.Result != nil
+			})
+		}
 		configureCacheMock := func() {
-			cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
-				Return(testValidCacheRef).Once()
-			cacheMock.On("Save", testValidCacheRef, pluginConf).
+			cacheMock.On("Save", testValidCacheRef, savedConf()).
 				Return(nil).Once()
 		}
 		successfullySave := func(withDeps bool) {
@@ -204,8 +276,12 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 			cleanupGetNS()
 			managerMock.On("DetachRepresentor", pluginConf).Return(nil).Once()
 		}
-		cleanupSetupVFConfig := func() {
+		cleanupApplyVFConfig := func() {
 			cleanupAttachRepresentor()
+			managerMock.On("ResetVFConfig", pluginConf).Return(nil).Once()
+		}
+		cleanupSetupVFConfig := func() {
+			cleanupApplyVFConfig()
 			netNSMock.On("Do", mock.Anything).Return(nil).Once()
 			managerMock.On("ReleaseVF",
 				pluginConf, testValidContIFNames, testValidContainerID, netNSMock).Return(nil).Once()
@@ -215,7 +291,21 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 			ipamMock.On("ExecDel", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
 		}
 		Context("Failed scenarios", func() {
+			It("Fail to load config", func() {
+				configMock.On("LoadConf", mock.Anything, mock.Anything).Return(errTest).Once()
+				Expect(plugin.CmdAdd(getValidCmdArgs())).To(HaveOccurred())
+			})
+			It("Device is busy", func() {
+				successfullyLoadConfig()
+				lockMock = &managerMocks.IPCLock{}
+				lockMock.On("LockWithTimeout", defaultLockTimeout).Return(manager.ErrLockTimeout).Once()
+				err := plugin.CmdAdd(getValidCmdArgs())
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*types.Error).Code).To(Equal(types.ErrTryAgainLater))
+			})
 			It("Fail to parse config", func() {
+				successfullyLoadConfig()
+				noJournal()
 				configMock.On("ParseConf", mock.Anything, mock.Anything).Return(errTest).Once()
 				Expect(plugin.CmdAdd(getValidCmdArgs())).To(HaveOccurred())
 			})
@@ -224,19 +314,54 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 				nsMock.On("GetNS", testValidNSPath).Return(nil, errTest).Once()
 				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
 			})
-			It("Failed to attach representor", func() {
+			It("Failed to resolve representor", func() {
 				successfullyGetNS(true)
+				managerMock.On("ResolveRepresentor", pluginConf).Return(errTest).Once()
+				cleanupGetNS()
+				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
+			})
+			It("Failed to save original state, should not write journal", func() {
+				successfullyGetNS(true)
+				managerMock.On("ResolveRepresentor", pluginConf).Return(nil).Once()
+				managerMock.On("SaveState", pluginConf).Return(errTest).Once()
+				cleanupGetNS()
+				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
+			})
+			It("Failed to attach representor", func() {
+				successfullySaveState(true)
+				journalStarted()
 				managerMock.On("AttachRepresentor", pluginConf).Return(errTest).Once()
 				cleanupGetNS()
 				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
 			})
-			It("Failed to ApplyVFConfig", func() {
+			It("Failed to get host interfaces", func() {
+				successfullyApplyVFConfig(true)
+				managerMock.On("SetupVF",
+					pluginConf, testValidContIFNames, testValidContainerID, netNSMock).
+					Return(testValidMAC, nil).Once()
+				managerMock.On("GetHostInterfaces", pluginConf).Return(nil, errTest).Once()
+				cleanupSetupVFConfig()
+				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
+			})
+			It("Failed to ApplyVFConfig, should reset options applied before the failure", func() {
 				successfullyAttachRepresentor(true)
 				managerMock.On("ApplyVFConfig", pluginConf).Return(errTest).Once()
-				cleanupAttachRepresentor()
+				cleanupApplyVFConfig()
+				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
+			})
+			It("Failed to ApplyVFConfig and ResetVFConfig, should keep journal", func() {
+				successfullySaveState(true)
+				cacheMock.On("GetJournalRef", testValidDeviceID).Return(testValidJournalRef).Once()
+				cacheMock.On("Save", testValidJournalRef, mock.Anything).Return(nil)
+				managerMock.On("AttachRepresentor", pluginConf).Return(nil).Once()
+				managerMock.On("ApplyVFConfig", pluginConf).Return(errTest).Once()
+				managerMock.On("ResetVFConfig", pluginConf).Return(errTest).Once()
+				managerMock.On("DetachRepresentor", pluginConf).Return(nil).Once()
+				cleanupGetNS()
 				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
+				cacheMock.AssertNotCalled(t, "Delete", testValidJournalRef)
 			})
-			It("Failed to SetupVF", func() {
+			It("Failed to SetupVF, should reset VF config", func() {
 				successfullyApplyVFConfig(true)
 				managerMock.On("SetupVF",
 					pluginConf, testValidContIFNames, testValidContainerID, netNSMock).
@@ -270,12 +395,20 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 				cleanupExecAdd()
 				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
 			})
+			It("Failed to write journal", func() {
+				successfullySaveState(true)
+				cacheMock.On("GetJournalRef", testValidDeviceID).Return(testValidJournalRef).Once()
+				cacheMock.On("Save", testValidJournalRef, mock.Anything).Return(errTest).Once()
+				cacheMock.On("Delete", testValidJournalRef).Return(nil).Once()
+				cleanupGetNS()
+				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
+			})
 			It("Failed save cache", func() {
 				successfullyConfigureIface(true)
-				cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
-					Return(testValidCacheRef).Once()
-				cacheMock.On("Save", testValidCacheRef, pluginConf).
-					Return(errTest).Once()
+				cacheMock.On("Save", testValidCacheRef, savedConf()).Run(func(args mock.Arguments) {
+					// cleanup is called for PluginConf with result
+					pluginConf.Result = args[1].(*localtypes.PluginConf).Result
+				}).Return(errTest).Once()
 				cleanupExecAdd()
 				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
 			})
@@ -293,21 +426,252 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 				cleanupGetNS()
 				Expect(plugin.CmdAdd(cmdArgs)).ToNot(HaveOccurred())
 			})
+			It("should lock VF and representor", func() {
+				var lockedNames []string
+				plugin.newIPCLock = func(name string) manager.IPCLock {
+					lockedNames = append(lockedNames, name)
+					return lockMock
+				}
+				successfullySave(true)
+				cleanupGetNS()
+				Expect(plugin.CmdAdd(cmdArgs)).ToNot(HaveOccurred())
+				Expect(lockedNames).To(Equal([]string{testValidDeviceID, testValidRepName}))
+			})
+			It("with pod identity, should set representor alias and altname", func() {
+				pluginConf.IPAM = types.IPAM{}
+				pluginConf.RepresentorAltName = true
+				cmdArgs.Args = "IgnoreUnknown=1;K8S_POD_NAMESPACE=default;K8S_POD_NAME=pod1"
+				successfullySetupVF(true)
+				configureCacheMock()
+				cleanupGetNS()
+				pluginConf.RepAlias = testValidRepAlias + " pod=default/pod1"
+				pluginConf.RepAltName = "default_pod1_" + testValidContIFNames
+				Expect(plugin.CmdAdd(cmdArgs)).ToNot(HaveOccurred())
+			})
+			It("with static FDB, should add FDB entries for the VF MAC", func() {
+				pluginConf.IPAM = types.IPAM{}
+				pluginConf.StaticFDB = true
+				successfullySetupVF(true)
+				managerMock.On("AddRepresentorFDB", pluginConf, testValidMAC).Return(nil).Once()
+				configureCacheMock()
+				cleanupGetNS()
+				Expect(plugin.CmdAdd(cmdArgs)).ToNot(HaveOccurred())
+			})
+			It("with neighSuppress, should add bridge neighbor entries for IPAM addresses", func() {
+				pluginConf.NeighSuppress = true
+				successfullySave(true)
+				managerMock.On("AddBridgeNeighbors", pluginConf, testValidMAC,
+					[]net.IP{net.ParseIP("192.168.100.0").To4()}).Return(nil).Once()
+				cleanupGetNS()
+				Expect(plugin.CmdAdd(cmdArgs)).ToNot(HaveOccurred())
+			})
+			It("with neighSuppress, failed to add bridge neighbor entries", func() {
+				pluginConf.NeighSuppress = true
+				successfullyConfigureIface(true)
+				managerMock.On("AddBridgeNeighbors", pluginConf, testValidMAC, mock.Anything).Return(errTest).Once()
+				cleanupExecAdd()
+				Expect(plugin.CmdAdd(cmdArgs)).To(MatchError(ContainSubstring("bridge neighbor entries")))
+			})
 			It("userspace driver", func() {
 				pluginConf.IsUserspaceDriver = true
 				successfullyApplyVFConfig(true)
+				successfullyGetHostInterfaces()
 				successfullyExecAdd(false)
 				successfullySave(false)
 				cleanupGetNS()
 				Expect(plugin.CmdAdd(cmdArgs)).ToNot(HaveOccurred())
 			})
+			It("with prevResult, should append interface and IPs", func() {
+				_, prevNet, _ := net.ParseCIDR("10.0.0.2/24")
+				pluginConf.PrevResult = &current.Result{
+					CNIVersion: "1.0.0",
+					Interfaces: []*current.Interface{{Name: "eth0", Sandbox: testValidNSPath}},
+					IPs:        []*current.IPConfig{{Address: *prevNet, Interface: current.Int(0)}},
+				}
+				successfullyExecAdd(true)
+				ipamMock.On("ConfigureIface", cmdArgs.IfName,
+					mock.MatchedBy(func(res *current.Result) bool {
+						return len(res.Interfaces) == 4 && res.Interfaces[3].Name == cmdArgs.IfName &&
+							len(res.IPs) == 1 && *res.IPs[0].Interface == 3
+					})).Return(nil).Once()
+				netNSMock.On("Do", mock.Anything).Return(func(f func(ns.NetNS) error) error {
+					return f(nil)
+				}).Once()
+				cacheMock.On("Save", testValidCacheRef, mock.MatchedBy(func(conf *localtypes.PluginConf) bool {
+					res := conf.Result
+					return res != nil && len(res.Interfaces) == 4 && res.Interfaces[0].Name == "eth0" &&
+						res.Interfaces[1].Name == testValidBridge && res.Interfaces[2].Name == testValidRepName &&
+						res.Interfaces[2].Sandbox == "" && res.Interfaces[3].Mac == testValidMAC &&
+						len(res.IPs) == 2 && *res.IPs[0].Interface == 0 && *res.IPs[1].Interface == 3
+				})).Return(nil).Once()
+				cleanupGetNS()
+				Expect(plugin.CmdAdd(cmdArgs)).ToNot(HaveOccurred())
+			})
+			It("with VLAN sub-interface, should configure IPAM and report the sub-interface", func() {
+				cmdArgs.StdinData = []byte(`{"name":"mynet","ipam":{"type":"host-local"}}`)
+				vlanStdin := []byte(`{"ipam":{"type":"static"},"name":"mynet"}`)
+				pluginConf.IPAM = types.IPAM{}
+				pluginConf.VlanInterfaces = []localtypes.VlanInterface{
+					{ID: 100, IPAM: map[string]interface{}{"type": "static"}}}
+				successfullyApplyVFConfig(true)
+				managerMock.On("SetupVF",
+					pluginConf, testValidContIFNames, testValidContainerID, netNSMock).Run(func(args mock.Arguments) {
+					conf := args[0].(*localtypes.PluginConf)
+					conf.ContVlanIfNames = []string{testValidContIFNames + ".100"}
+					pluginConf.ContVlanIfNames = conf.ContVlanIfNames
+				}).Return(testValidMAC, nil).Once()
+				successfullyGetHostInterfaces()
+				netNSMock.On("Path").Return(testValidNSPath).Once()
+				var ipamIfName string
+				ipamMock.On("ExecAdd", "static", vlanStdin).Run(func(_ mock.Arguments) {
+					ipamIfName = os.Getenv("CNI_IFNAME")
+				}).Return(getValidIPAMResult(), nil).Once()
+				ipamMock.On("ConfigureIface", testValidContIFNames+".100",
+					mock.MatchedBy(func(res *current.Result) bool {
+						return len(res.Interfaces) == 4 && len(res.IPs) == 1 && *res.IPs[0].Interface == 3
+					})).Return(nil).Once()
+				netNSMock.On("Do", mock.Anything).Return(func(f func(ns.NetNS) error) error {
+					return f(nil)
+				}).Once()
+				cacheMock.On("Save", testValidCacheRef, mock.MatchedBy(func(conf *localtypes.PluginConf) bool {
+					res := conf.Result
+					return res != nil && len(res.Interfaces) == 4 &&
+						res.Interfaces[3].Name == testValidContIFNames+".100" &&
+						res.Interfaces[3].Mac == testValidMAC && res.Interfaces[3].Sandbox == testValidNSPath &&
+						len(res.IPs) == 1 && *res.IPs[0].Interface == 3
+				})).Return(nil).Once()
+				cleanupGetNS()
+				Expect(plugin.CmdAdd(cmdArgs)).ToNot(HaveOccurred())
+				Expect(ipamIfName).To(Equal(testValidContIFNames + ".100"))
+			})
+			It("journal records steps before they are executed, together with the state to undo them", func() {
+				// representor and the original state are not known until they are resolved and saved
+				pluginConf.Representor = ""
+				successfullyGetNS(true)
+				managerMock.On("ResolveRepresentor", pluginConf).Run(func(args mock.Arguments) {
+					args[0].(*localtypes.PluginConf).Representor = testValidRepName
+					pluginConf.Representor = testValidRepName
+				}).Return(nil).Once()
+				managerMock.On("SaveState", pluginConf).Run(func(args mock.Arguments) {
+					for _, conf := range []*localtypes.PluginConf{args[0].(*localtypes.PluginConf), pluginConf} {
+						conf.OrigRepState = localtypes.RepState{MTU: 1500, Saved: true}
+						conf.OrigVfState.EffectiveMAC = testValidMAC
+						conf.OrigVfState.MTU = 1500
+						conf.OrigVfState.Saved = true
+						conf.OrigVfState.NetdevSaved = true
+					}
+				}).Return(nil).Once()
+				var entries []journalEntry
+				cacheMock.On("GetJournalRef", testValidDeviceID).Return(testValidJournalRef).Once()
+				cacheMock.On("Save", testValidJournalRef, mock.Anything).Run(func(args mock.Arguments) {
+					// keep the persisted content, the entry is changed by the next steps
+					data, err := json.Marshal(args[1])
+					Expect(err).NotTo(HaveOccurred())
+					entry := journalEntry{}
+					Expect(json.Unmarshal(data, &entry)).To(Succeed())
+					entries = append(entries, entry)
+				}).Return(nil)
+				cacheMock.On("Delete", testValidJournalRef).Return(nil).Once()
+				managerMock.On("AttachRepresentor", pluginConf).Return(nil).Once()
+				successfullyApplyVFConfig(false)
+				successfullySave(false)
+				successfullySetupVF(false)
+				successfullyConfigureIface(false)
+				successfullyExecAdd(false)
+				cleanupGetNS()
+				Expect(plugin.CmdAdd(cmdArgs)).ToNot(HaveOccurred())
+				var steps [][]string
+				for _, entry := range entries {
+					steps = append(steps, entry.Steps)
+					Expect(entry.Conf.Representor).To(Equal(testValidRepName))
+					Expect(entry.Conf.OrigRepState.Saved).To(BeTrue())
+					Expect(entry.Conf.OrigVfState.Saved).To(BeTrue())
+					Expect(entry.Conf.OrigVfState.NetdevSaved).To(BeTrue())
+					Expect(entry.Conf.OrigVfState.EffectiveMAC).To(Equal(testValidMAC))
+					Expect(entry.Conf.OrigVfState.MTU).To(Equal(1500))
+				}
+				Expect(steps).To(Equal([][]string{
+					{stepAttachRepresentor},
+					{stepAttachRepresentor, stepApplyVFConfig},
+					{stepAttachRepresentor, stepApplyVFConfig, stepSetupVF},
+					{stepAttachRepresentor, stepApplyVFConfig, stepSetupVF},
+				}))
+			})
+		})
+		Context("Incomplete journal exists", func() {
+			var journalConf *localtypes.PluginConf
+
+			JustBeforeEach(func() {
+				successfullyLoadConfig()
+				journalConf = getValidPluginConf()
+				cacheMock.On("GetJournalRef", testValidDeviceID).Return(testValidJournalRef).Once()
+				cacheMock.On("Load", testValidJournalRef, mock.Anything).Run(func(args mock.Arguments) {
+					*args[1].(*journalEntry) = journalEntry{
+						ContainerID: "oldcid",
+						IfName:      cmdArgs.IfName,
+						Netns:       "/proc/1/ns/net",
+						Steps:       []string{stepAttachRepresentor, stepApplyVFConfig, stepSetupVF},
+						Conf:        journalConf,
+					}
+				}).Return(nil).Once()
+				cacheMock.On("GetStateRef", pluginConf.Name, "oldcid", cmdArgs.IfName).
+					Return(cache.StateRef("oldref")).Once()
+				cacheMock.On("Delete", testValidJournalRef).Return(nil).Once()
+				configMock.On("ParseConf", mock.Anything, mock.Anything).Return(errTest).Once()
+			})
+			It("should undo steps in reverse order", func() {
+				cacheMock.On("Load", cache.StateRef("oldref"), mock.Anything).Return(errTest).Once()
+				nsMock.On("GetNS", "/proc/1/ns/net").Return(nil, ns.NSPathNotExistErr{}).Once()
+				restore := managerMock.On("RestoreHostVF", journalConf).Return(nil).Once()
+				reset := managerMock.On("ResetVFConfig", journalConf).Return(nil).Once().NotBefore(restore)
+				managerMock.On("DetachRepresentor", journalConf).Return(nil).Once().NotBefore(reset)
+				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
+			})
+			It("attachment was cached, should only remove journal", func() {
+				cacheMock.On("Load", cache.StateRef("oldref"), mock.Anything).Return(nil).Once()
+				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
+			})
+		})
+		Context("Attachment already exists", func() {
+			var cachedConf *localtypes.PluginConf
+
+			JustBeforeEach(func() {
+				successfullyParseConfig(true)
+				nsMock.On("GetNS", testValidNSPath).Return(netNSMock, nil).Once()
+				cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
+					Return(testValidCacheRef).Once()
+				cachedConf = getValidPluginConf()
+				cachedConf.Result = getValidIPAMResult().(*current.Result)
+				cacheMock.On("Load", testValidCacheRef, mock.Anything).Run(func(args mock.Arguments) {
+					*args[1].(*localtypes.PluginConf) = *cachedConf
+				}).Return(nil).Once()
+				cleanupGetNS()
+			})
+			It("attachment is intact, should return cached result", func() {
+				managerMock.On("CheckRepresentor", cachedConf).Return(nil).Once()
+				managerMock.On("CheckVF", cachedConf, cmdArgs.IfName, cachedConf.Result, netNSMock).
+					Return(nil).Once()
+				Expect(plugin.CmdAdd(cmdArgs)).ToNot(HaveOccurred())
+			})
+			It("attachment is broken (failure)", func() {
+				managerMock.On("CheckRepresentor", cachedConf).Return(errTest).Once()
+				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
+			})
+			It("cached state has no result (failure)", func() {
+				cachedConf.Result = nil
+				Expect(plugin.CmdAdd(cmdArgs)).To(HaveOccurred())
+			})
 		})
+
 		Context("MAC address configuration", func() {
 
 			var updatedPluginConf *localtypes.PluginConf
 
 			JustBeforeEach(func() {
 				successfullyGetNS(true)
+				managerMock.On("ResolveRepresentor", mock.Anything).Return(nil).Once()
+				managerMock.On("SaveState", mock.Anything).Return(nil).Once()
+				journalStarted()
 				cleanupGetNS()
 				// workaround to access pluginConf
 				managerMock.On("AttachRepresentor", mock.Anything).Run(func(args mock.Arguments) {
@@ -426,8 +790,14 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 			}).Return(nil).Once()
 		}
 
+		noJournal := func() {
+			cacheMock.On("GetJournalRef", testValidDeviceID).Return(testValidJournalRef).Once()
+			cacheMock.On("Load", testValidJournalRef, mock.Anything).Return(errTest).Once()
+		}
+
 		successfullyLoadCache := func() {
 			successfullyLoadConfig()
+			noJournal()
 			cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
 				Return(testValidCacheRef).Once()
 			cacheMock.On("Load", testValidCacheRef, mock.Anything).Run(func(args mock.Arguments) {
@@ -435,6 +805,23 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 			}).Return(nil).Once()
 		}
 
+		cacheMiss := func() {
+			successfullyLoadConfig()
+			noJournal()
+			cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
+				Return(testValidCacheRef).Once()
+			cacheMock.On("Load", testValidCacheRef, mock.Anything).Return(errTest).Once()
+		}
+
+		noOtherAttachments := func() {
+			cacheMock.On("List").Return([]cache.StateRef{"othernet-cid2-net1"}, nil).Once()
+			cacheMock.On("Load", cache.StateRef("othernet-cid2-net1"), mock.Anything).
+				Run(func(args mock.Arguments) {
+					*args[1].(*localtypes.PluginConf) = localtypes.PluginConf{
+						NetConf: localtypes.NetConf{DeviceID: "0000:af:06.2"}}
+				}).Return(nil).Once()
+		}
+
 		successfullyDetachRepresentor := func() {
 			successfullyLoadCache()
 			managerMock.On("DetachRepresentor", pluginConf).Return(nil).Once()
@@ -477,26 +864,49 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 					Return(errTest)
 				Expect(plugin.CmdDel(cmdArgs)).To(HaveOccurred())
 			})
-			It("Failed to load cache", func() {
+			It("Device is busy", func() {
 				successfullyLoadConfig()
-				cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
-					Return(testValidCacheRef).Once()
-				cacheMock.On("Load", testValidCacheRef, mock.Anything).
-					Return(errTest).Once()
+				lockMock = &managerMocks.IPCLock{}
+				lockMock.On("LockWithTimeout", defaultLockTimeout).Return(manager.ErrLockTimeout).Once()
+				err := plugin.CmdDel(cmdArgs)
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*types.Error).Code).To(Equal(types.ErrTryAgainLater))
+			})
+			It("Failed to load cache and recover state", func() {
+				cacheMiss()
+				noOtherAttachments()
+				configMock.On("ReconstructConf", cmdArgs.StdinData, mock.Anything).Return(errTest).Once()
 				Expect(plugin.CmdDel(cmdArgs)).ToNot(HaveOccurred())
 			})
-			It("Failed to call IPAM del", func() {
+			It("Failed to call IPAM del, should still release VF and reset VF config", func() {
 				successfullyDetachRepresentor()
 				ipamMock.On("ExecDel", pluginConf.IPAM.Type, cmdArgs.StdinData).
 					Return(errTest).Once()
-				Expect(plugin.CmdDel(cmdArgs)).To(HaveOccurred())
+				nsMock.On("GetNS", cmdArgs.Netns).Return(netNSMock, nil)
+				managerMock.On("ReleaseVF", pluginConf, cmdArgs.IfName, cmdArgs.ContainerID, netNSMock).
+					Return(nil).Once()
+				managerMock.On("ResetVFConfig", pluginConf).Return(nil).Once()
+				cleanupClose()
+				Expect(plugin.CmdDel(cmdArgs)).To(MatchError(errTest))
+				cacheMock.AssertNotCalled(t, "Delete", testValidCacheRef)
 			})
-			It("Failed to ReleaseVF", func() {
+			It("Failed to ReleaseVF, should still reset VF config", func() {
 				successfullyGetNS()
 				managerMock.On("ReleaseVF", pluginConf, cmdArgs.IfName, cmdArgs.ContainerID, netNSMock).
 					Return(errTest)
+				managerMock.On("ResetVFConfig", pluginConf).Return(nil).Once()
 				cleanupClose()
-				Expect(plugin.CmdDel(cmdArgs)).To(HaveOccurred())
+				Expect(plugin.CmdDel(cmdArgs)).To(MatchError(errTest))
+			})
+			It("Failed to ReleaseVF and ResetVFConfig, should return both errors", func() {
+				successfullyGetNS()
+				managerMock.On("ReleaseVF", pluginConf, cmdArgs.IfName, cmdArgs.ContainerID, netNSMock).
+					Return(errTest)
+				managerMock.On("ResetVFConfig", pluginConf).Return(errors.New("reset error")).Once()
+				cleanupClose()
+				err := plugin.CmdDel(cmdArgs)
+				Expect(err).To(MatchError(errTest))
+				Expect(err).To(MatchError(ContainSubstring("reset error")))
 			})
 			It("Failed to ResetVFConfig", func() {
 				successfullyReleaseVF()
@@ -505,15 +915,43 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 				cleanupClose()
 				Expect(plugin.CmdDel(cmdArgs)).To(HaveOccurred())
 			})
+			It("Failed to open NS", func() {
+				successfullyExecDel()
+				nsMock.On("GetNS", cmdArgs.Netns).Return(nil, errTest).Once()
+				managerMock.On("ResetVFConfig", pluginConf).Return(nil).Once()
+				Expect(plugin.CmdDel(cmdArgs)).To(HaveOccurred())
+			})
 		})
 		Context("Successful scenarios", func() {
-			It("no NetNs provided", func() {
+			It("no NetNs provided, should do host-side cleanup", func() {
 				cmdArgs.Netns = ""
+				successfullyExecDel()
+				managerMock.On("RestoreHostVF", pluginConf).Return(nil).Once()
+				managerMock.On("ResetVFConfig", pluginConf).Return(nil).Once()
+				cacheMock.On("Delete", testValidCacheRef).Return(nil).Once()
 				Expect(plugin.CmdDel(cmdArgs)).NotTo(HaveOccurred())
 			})
-			It("Failed to get NS, should return no error", func() {
+			It("NS doesn't exist, should restore VF in host NS", func() {
 				successfullyExecDel()
 				nsMock.On("GetNS", cmdArgs.Netns).Return(nil, ns.NSPathNotExistErr{}).Once()
+				managerMock.On("RestoreHostVF", pluginConf).Return(nil).Once()
+				managerMock.On("ResetVFConfig", pluginConf).Return(nil).Once()
+				cacheMock.On("Delete", testValidCacheRef).Return(nil).Once()
+				Expect(plugin.CmdDel(cmdArgs)).NotTo(HaveOccurred())
+			})
+			It("NS doesn't exist and VF not found in host NS, should return no error", func() {
+				successfullyExecDel()
+				nsMock.On("GetNS", cmdArgs.Netns).Return(nil, ns.NSPathNotExistErr{}).Once()
+				managerMock.On("RestoreHostVF", pluginConf).Return(errTest).Once()
+				managerMock.On("ResetVFConfig", pluginConf).Return(nil).Once()
+				cacheMock.On("Delete", testValidCacheRef).Return(nil).Once()
+				Expect(plugin.CmdDel(cmdArgs)).NotTo(HaveOccurred())
+			})
+			It("userspace driver", func() {
+				pluginConf.IsUserspaceDriver = true
+				successfullyExecDel()
+				managerMock.On("ResetVFConfig", pluginConf).Return(nil).Once()
+				cacheMock.On("Delete", testValidCacheRef).Return(nil).Once()
 				Expect(plugin.CmdDel(cmdArgs)).NotTo(HaveOccurred())
 			})
 			It("success", func() {
@@ -521,15 +959,391 @@ var _ = Describe("Plugin - test CNI command flows", func() {
 				cleanupCacheDelete()
 				Expect(plugin.CmdDel(cmdArgs)).NotTo(HaveOccurred())
 			})
+			It("with VLAN sub-interface, should release IPAM of the sub-interface", func() {
+				cmdArgs.StdinData = []byte(`{"name":"mynet","ipam":{"type":"host-local"}}`)
+				pluginConf.VlanInterfaces = []localtypes.VlanInterface{
+					{ID: 100, IPAM: map[string]interface{}{"type": "static"}}}
+				ipamMock.On("ExecDel", "static", []byte(`{"ipam":{"type":"static"},"name":"mynet"}`)).
+					Return(nil).Once()
+				successfullyResetVFConfig()
+				cleanupCacheDelete()
+				Expect(plugin.CmdDel(cmdArgs)).NotTo(HaveOccurred())
+			})
+			It("cache is missing, VF is reused by another attachment, should do nothing", func() {
+				cacheMiss()
+				cacheMock.On("List").Return([]cache.StateRef{testValidCacheRef, "othernet-cid2-net1"}, nil).Once()
+				cacheMock.On("Load", cache.StateRef("othernet-cid2-net1"), mock.Anything).
+					Run(func(args mock.Arguments) {
+						*args[1].(*localtypes.PluginConf) = localtypes.PluginConf{
+							NetConf: localtypes.NetConf{DeviceID: testValidDeviceID}}
+					}).Return(nil).Once()
+				Expect(plugin.CmdDel(cmdArgs)).NotTo(HaveOccurred())
+				configMock.AssertNotCalled(t, "ReconstructConf", mock.Anything, mock.Anything)
+				managerMock.AssertNotCalled(t, "DetachRepresentor", mock.Anything)
+				managerMock.AssertNotCalled(t, "ResetVFConfig", mock.Anything)
+			})
+			It("cache is missing, failed to list cache, should do nothing", func() {
+				cacheMiss()
+				cacheMock.On("List").Return(nil, errTest).Once()
+				Expect(plugin.CmdDel(cmdArgs)).NotTo(HaveOccurred())
+				managerMock.AssertNotCalled(t, "DetachRepresentor", mock.Anything)
+			})
+			It("cache is missing, should recover state", func() {
+				cacheMiss()
+				noOtherAttachments()
+				configMock.On("ReconstructConf", cmdArgs.StdinData, mock.Anything).Run(func(args mock.Arguments) {
+					*args[1].(*localtypes.PluginConf) = *pluginConf
+				}).Return(nil).Once()
+				nsMock.On("GetNS", cmdArgs.Netns).Return(netNSMock, nil).Twice()
+				managerMock.On("RecoverState", pluginConf, cmdArgs.IfName, netNSMock).Return(nil).Once()
+				managerMock.On("DetachRepresentor", pluginConf).Return(nil).Once()
+				ipamMock.On("ExecDel", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
+				managerMock.On("ReleaseVF", pluginConf, cmdArgs.IfName, cmdArgs.ContainerID, netNSMock).
+					Return(nil)
+				managerMock.On("ResetVFConfig", pluginConf).Return(nil)
+				cleanupClose()
+				Expect(plugin.CmdDel(cmdArgs)).NotTo(HaveOccurred())
+			})
 		})
 	})
 	Describe("CmdCheck", func() {
+		successfullyLoadConfig := func() {
+			configMock.On("LoadConf", cmdArgs.StdinData, mock.Anything).Run(func(args mock.Arguments) {
+				netConf := args[1].(*localtypes.NetConf)
+				*netConf = pluginConf.NetConf
+				netConf.RawPrevResult = getValidPrevResult()
+			}).Return(nil).Once()
+		}
+
+		successfullyLoadCache := func() {
+			successfullyLoadConfig()
+			cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
+				Return(testValidCacheRef).Once()
+			cacheMock.On("Load", testValidCacheRef, mock.Anything).Run(func(args mock.Arguments) {
+				*args[1].(*localtypes.PluginConf) = *pluginConf
+			}).Return(nil).Once()
+		}
+
+		successfullyCheckRepresentor := func() {
+			successfullyLoadCache()
+			ipamMock.On("ExecCheck", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
+			managerMock.On("CheckRepresentor", pluginConf).Return(nil).Once()
+		}
+
+		successfullyGetNS := func() {
+			successfullyCheckRepresentor()
+			nsMock.On("GetNS", cmdArgs.Netns).Return(netNSMock, nil).Once()
+			netNSMock.On("Close").Return(nil).Once()
+		}
+
+		Context("Failed scenarios", func() {
+			It("Failed to load config", func() {
+				configMock.On("LoadConf", cmdArgs.StdinData, mock.Anything).Return(errTest).Once()
+				err := plugin.CmdCheck(cmdArgs)
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*types.Error).Code).To(Equal(uint(types.ErrInvalidNetworkConfig)))
+			})
+			It("prevResult is missing", func() {
+				configMock.On("LoadConf", cmdArgs.StdinData, mock.Anything).Run(func(args mock.Arguments) {
+					*args[1].(*localtypes.NetConf) = pluginConf.NetConf
+				}).Return(nil).Once()
+				err := plugin.CmdCheck(cmdArgs)
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*types.Error).Code).To(Equal(uint(types.ErrInvalidNetworkConfig)))
+			})
+			It("Failed to load cache", func() {
+				successfullyLoadConfig()
+				cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
+					Return(testValidCacheRef).Once()
+				cacheMock.On("Load", testValidCacheRef, mock.Anything).Return(errTest).Once()
+				err := plugin.CmdCheck(cmdArgs)
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*types.Error).Code).To(Equal(uint(types.ErrInternal)))
+			})
+			It("IPAM check failed", func() {
+				successfullyLoadCache()
+				ipamMock.On("ExecCheck", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(errTest).Once()
+				Expect(plugin.CmdCheck(cmdArgs)).To(HaveOccurred())
+			})
+			It("Representor check failed", func() {
+				successfullyLoadCache()
+				ipamMock.On("ExecCheck", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
+				managerMock.On("CheckRepresentor", pluginConf).Return(errTest).Once()
+				err := plugin.CmdCheck(cmdArgs)
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*types.Error).Code).To(Equal(uint(types.ErrInternal)))
+			})
+			It("Representor doesn't match configuration", func() {
+				successfullyLoadCache()
+				ipamMock.On("ExecCheck", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
+				managerMock.On("CheckRepresentor", pluginConf).
+					Return(fmt.Errorf("%w: representor is down", manager.ErrStateMismatch)).Once()
+				err := plugin.CmdCheck(cmdArgs)
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*types.Error).Code).To(Equal(uint(types.ErrInvalidNetworkConfig)))
+			})
+			It("VF check failed", func() {
+				successfullyGetNS()
+				managerMock.On("CheckVF", pluginConf, cmdArgs.IfName, mock.Anything, netNSMock).
+					Return(errTest).Once()
+				err := plugin.CmdCheck(cmdArgs)
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*types.Error).Code).To(Equal(uint(types.ErrInternal)))
+			})
+			It("VF doesn't match configuration", func() {
+				successfullyGetNS()
+				managerMock.On("CheckVF", pluginConf, cmdArgs.IfName, mock.Anything, netNSMock).
+					Return(fmt.Errorf("%w: VF MTU mismatch", manager.ErrStateMismatch)).Once()
+				err := plugin.CmdCheck(cmdArgs)
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*types.Error).Code).To(Equal(uint(types.ErrInvalidNetworkConfig)))
+			})
+		})
 		Context("Successful scenarios", func() {
-			It("should not fail", func() {
+			It("success", func() {
+				successfullyGetNS()
+				managerMock.On("CheckVF", pluginConf, cmdArgs.IfName,
+					mock.MatchedBy(func(result *current.Result) bool {
+						return len(result.Interfaces) == 1 && len(result.IPs) == 1
+					}), netNSMock).Return(nil).Once()
+				Expect(plugin.CmdCheck(cmdArgs)).NotTo(HaveOccurred())
+			})
+			It("userspace driver", func() {
+				pluginConf.IsUserspaceDriver = true
+				successfullyCheckRepresentor()
 				Expect(plugin.CmdCheck(cmdArgs)).NotTo(HaveOccurred())
 			})
 		})
 	})
+	Describe("CmdStatus", func() {
+		successfullyLoadConfig := func() {
+			configMock.On("LoadConf", cmdArgs.StdinData, mock.Anything).Run(func(args mock.Arguments) {
+				*args[1].(*localtypes.NetConf) = pluginConf.NetConf
+			}).Return(nil).Once()
+		}
+
+		Context("Failed scenarios", func() {
+			It("Failed to load config", func() {
+				configMock.On("LoadConf", cmdArgs.StdinData, mock.Anything).Return(errTest).Once()
+				err := plugin.CmdStatus(cmdArgs)
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*types.Error).Code).To(Equal(uint(types.ErrInvalidNetworkConfig)))
+			})
+			It("Invalid bridge configuration", func() {
+				pluginConf.Bridge = "br1,,br2"
+				successfullyLoadConfig()
+				err := plugin.CmdStatus(cmdArgs)
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*types.Error).Code).To(Equal(uint(types.ErrInvalidNetworkConfig)))
+			})
+			It("Host is not ready", func() {
+				successfullyLoadConfig()
+				managerMock.On("CheckStatus", &pluginConf.NetConf, []string{testValidBridge}).
+					Return(errTest).Once()
+				err := plugin.CmdStatus(cmdArgs)
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*types.Error).Code).To(Equal(errPluginNotAvailable))
+			})
+			It("IPAM status failed", func() {
+				successfullyLoadConfig()
+				managerMock.On("CheckStatus", &pluginConf.NetConf, []string{testValidBridge}).
+					Return(nil).Once()
+				ipamMock.On("ExecStatus", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(errTest).Once()
+				Expect(plugin.CmdStatus(cmdArgs)).To(HaveOccurred())
+			})
+		})
+		Context("Successful scenarios", func() {
+			It("success", func() {
+				successfullyLoadConfig()
+				managerMock.On("CheckStatus", &pluginConf.NetConf, []string{testValidBridge}).
+					Return(nil).Once()
+				ipamMock.On("ExecStatus", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
+				Expect(plugin.CmdStatus(cmdArgs)).NotTo(HaveOccurred())
+			})
+			It("no IPAM", func() {
+				pluginConf.IPAM = types.IPAM{}
+				successfullyLoadConfig()
+				managerMock.On("CheckStatus", &pluginConf.NetConf, []string{testValidBridge}).
+					Return(nil).Once()
+				Expect(plugin.CmdStatus(cmdArgs)).NotTo(HaveOccurred())
+			})
+		})
+	})
+	Describe("CmdGC", func() {
+		var (
+			staleConf      *localtypes.PluginConf
+			otherConf      *localtypes.PluginConf
+			staleCacheRef  cache.StateRef
+			otherCacheRef  cache.StateRef
+			journalRefs    []cache.StateRef
+			validAttachRef = testValidCacheRef
+		)
+
+		BeforeEach(func() {
+			journalRefs = nil
+			staleCacheRef = "/var/lib/cni/accelerated-bridge/mynet-stale-net1"
+			otherCacheRef = "/var/lib/cni/accelerated-bridge/othernet-cid-net1"
+		})
+
+		successfullyLoadConfig := func() {
+			configMock.On("LoadConf", cmdArgs.StdinData, mock.Anything).Run(func(args mock.Arguments) {
+				netConf := args[1].(*localtypes.NetConf)
+				*netConf = pluginConf.NetConf
+				netConf.ValidAttachments = []types.GCAttachment{
+					{ContainerID: cmdArgs.ContainerID, IfName: cmdArgs.IfName}}
+			}).Return(nil).Once()
+			cacheMock.On("GetStateRef", pluginConf.Name, cmdArgs.ContainerID, cmdArgs.IfName).
+				Return(validAttachRef).Once()
+		}
+
+		loadCache := func(ref cache.StateRef, conf *localtypes.PluginConf) {
+			cacheMock.On("Load", ref, mock.Anything).Run(func(args mock.Arguments) {
+				*args[1].(*localtypes.PluginConf) = *conf
+			}).Return(nil).Once()
+		}
+
+		successfullyListCache := func() {
+			successfullyLoadConfig()
+			staleConf = getValidPluginConf()
+			staleConf.DeviceID = "0000:af:06.2"
+			otherConf = getValidPluginConf()
+			otherConf.Name = "othernet"
+			otherConf.DeviceID = "0000:af:06.3"
+			cacheMock.On("List").Return(
+				[]cache.StateRef{validAttachRef, staleCacheRef, otherCacheRef}, nil).Once()
+			loadCache(validAttachRef, pluginConf)
+			loadCache(staleCacheRef, staleConf)
+			loadCache(otherCacheRef, otherConf)
+			cacheMock.On("ListJournals").Return(journalRefs, nil).Once()
+		}
+
+		// stale attachment is loaded and cache is listed again when the stale attachment is released
+		successfullyRelistCache := func(newRefs ...cache.StateRef) {
+			loadCache(staleCacheRef, staleConf)
+			cacheMock.On("List").Return(
+				append([]cache.StateRef{validAttachRef, staleCacheRef, otherCacheRef}, newRefs...), nil).Once()
+			loadCache(validAttachRef, pluginConf)
+			loadCache(otherCacheRef, otherConf)
+		}
+
+		Context("Failed scenarios", func() {
+			It("Failed to load config", func() {
+				configMock.On("LoadConf", cmdArgs.StdinData, mock.Anything).Return(errTest).Once()
+				Expect(plugin.CmdGC(cmdArgs)).To(HaveOccurred())
+			})
+			It("Failed to list cache", func() {
+				successfullyLoadConfig()
+				cacheMock.On("List").Return(nil, errTest).Once()
+				Expect(plugin.CmdGC(cmdArgs)).To(HaveOccurred())
+			})
+			It("Failed to list cache under the device lock, should keep cache", func() {
+				successfullyListCache()
+				loadCache(staleCacheRef, staleConf)
+				cacheMock.On("List").Return(nil, errTest).Once()
+				ipamMock.On("ExecGC", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
+				Expect(plugin.CmdGC(cmdArgs)).To(HaveOccurred())
+			})
+			It("Failed to reset VF config, should keep cache", func() {
+				successfullyListCache()
+				successfullyRelistCache()
+				managerMock.On("DetachRepresentor", staleConf).Return(nil).Once()
+				managerMock.On("ResetVFConfig", staleConf).Return(errTest).Once()
+				ipamMock.On("ExecGC", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
+				Expect(plugin.CmdGC(cmdArgs)).To(HaveOccurred())
+			})
+			It("Failed to call IPAM GC", func() {
+				successfullyListCache()
+				successfullyRelistCache()
+				managerMock.On("DetachRepresentor", staleConf).Return(nil).Once()
+				managerMock.On("ResetVFConfig", staleConf).Return(nil).Once()
+				cacheMock.On("Delete", staleCacheRef).Return(nil).Once()
+				ipamMock.On("ExecGC", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(errTest).Once()
+				Expect(plugin.CmdGC(cmdArgs)).To(HaveOccurred())
+			})
+		})
+		Context("Successful scenarios", func() {
+			It("release stale attachment", func() {
+				successfullyListCache()
+				successfullyRelistCache()
+				managerMock.On("DetachRepresentor", staleConf).Return(nil).Once()
+				managerMock.On("ResetVFConfig", staleConf).Return(nil).Once()
+				cacheMock.On("Delete", staleCacheRef).Return(nil).Once()
+				ipamMock.On("ExecGC", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
+				Expect(plugin.CmdGC(cmdArgs)).NotTo(HaveOccurred())
+			})
+			It("stale attachment is released by DEL completed after listing, should do nothing", func() {
+				successfullyListCache()
+				cacheMock.On("Load", staleCacheRef, mock.Anything).Return(errTest).Once()
+				ipamMock.On("ExecGC", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
+				Expect(plugin.CmdGC(cmdArgs)).NotTo(HaveOccurred())
+			})
+			It("with VLAN sub-interface, should keep sub-interfaces of valid attachments", func() {
+				cmdArgs.StdinData = []byte(`{"name":"mynet","cni.dev/valid-attachments":` +
+					`[{"containerID":"cid","ifname":"net1"}]}`)
+				pluginConf.IPAM = types.IPAM{}
+				pluginConf.VlanInterfaces = []localtypes.VlanInterface{
+					{ID: 100, IPAM: map[string]interface{}{"type": "static"}}}
+				successfullyListCache()
+				successfullyRelistCache()
+				managerMock.On("DetachRepresentor", staleConf).Return(nil).Once()
+				managerMock.On("ResetVFConfig", staleConf).Return(nil).Once()
+				cacheMock.On("Delete", staleCacheRef).Return(nil).Once()
+				ipamMock.On("ExecGC", "static", []byte(`{"cni.dev/valid-attachments":`+
+					`[{"containerID":"cid","ifname":"net1"},{"containerID":"cid","ifname":"net1.100"}],`+
+					`"ipam":{"type":"static"},"name":"mynet"}`)).Return(nil).Once()
+				Expect(plugin.CmdGC(cmdArgs)).NotTo(HaveOccurred())
+			})
+			It("incomplete journal for the network, should undo steps", func() {
+				journalRefs = []cache.StateRef{testValidJournalRef, "journal/0000:af:06.4"}
+				successfullyListCache()
+				successfullyRelistCache()
+				managerMock.On("DetachRepresentor", staleConf).Return(nil).Once()
+				managerMock.On("ResetVFConfig", staleConf).Return(nil).Once()
+				cacheMock.On("Delete", staleCacheRef).Return(nil).Once()
+				entry := journalEntry{
+					ContainerID: "oldcid",
+					IfName:      cmdArgs.IfName,
+					Steps:       []string{stepAttachRepresentor},
+					Conf:        getValidPluginConf(),
+				}
+				otherEntry := journalEntry{Conf: otherConf}
+				loadJournal := func(ref cache.StateRef, entry journalEntry) {
+					cacheMock.On("Load", ref, mock.Anything).Run(func(args mock.Arguments) {
+						*args[1].(*journalEntry) = entry
+					}).Return(nil)
+				}
+				loadJournal(testValidJournalRef, entry)
+				loadJournal("journal/0000:af:06.4", otherEntry)
+				cacheMock.On("GetJournalRef", testValidDeviceID).Return(testValidJournalRef).Once()
+				cacheMock.On("GetStateRef", pluginConf.Name, "oldcid", cmdArgs.IfName).
+					Return(cache.StateRef("oldref")).Once()
+				cacheMock.On("Load", cache.StateRef("oldref"), mock.Anything).Return(errTest).Once()
+				managerMock.On("DetachRepresentor", entry.Conf).Return(nil).Once()
+				cacheMock.On("Delete", testValidJournalRef).Return(nil).Once()
+				ipamMock.On("ExecGC", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
+				Expect(plugin.CmdGC(cmdArgs)).NotTo(HaveOccurred())
+			})
+			It("VF of stale attachment is in use, should only remove cache", func() {
+				successfullyListCache()
+				successfullyRelistCache()
+				staleConf.DeviceID = otherConf.DeviceID
+				cacheMock.On("Delete", staleCacheRef).Return(nil).Once()
+				ipamMock.On("ExecGC", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
+				Expect(plugin.CmdGC(cmdArgs)).NotTo(HaveOccurred())
+			})
+			It("VF of stale attachment is reused by ADD completed after listing, should only remove cache", func() {
+				successfullyListCache()
+				newCacheRef := cache.StateRef("/var/lib/cni/accelerated-bridge/mynet-newcid-net1")
+				newConf := getValidPluginConf()
+				newConf.DeviceID = staleConf.DeviceID
+				successfullyRelistCache(newCacheRef)
+				loadCache(newCacheRef, newConf)
+				cacheMock.On("Delete", staleCacheRef).Return(nil).Once()
+				ipamMock.On("ExecGC", pluginConf.IPAM.Type, cmdArgs.StdinData).Return(nil).Once()
+				Expect(plugin.CmdGC(cmdArgs)).NotTo(HaveOccurred())
+			})
+		})
+	})
 })
 
 var _ = Describe("Plugin - test plugin initialization", func() {
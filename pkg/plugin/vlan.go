@@ -0,0 +1,123 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	localtypes "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+)
+
+// validAttachmentsKey is the key of the valid attachments list in the GC configuration
+const validAttachmentsKey = "cni.dev/valid-attachments"
+
+// configureVlanIPAM runs the IPAM plugins configured for VLAN sub-interfaces,
+// VLAN sub-interfaces follow the container interface in the result
+func (p *Plugin) configureVlanIPAM(cmdCtx *cmdContext) error {
+	for i := range cmdCtx.pluginConf.VlanInterfaces {
+		vlanIf := &cmdCtx.pluginConf.VlanInterfaces[i]
+		if vlanIf.IPAMType() == "" {
+			continue
+		}
+		stdin, err := vlanIPAMStdin(cmdCtx.args.StdinData, vlanIf)
+		if err != nil {
+			return err
+		}
+		err = p.execIPAMAdd(cmdCtx, vlanIf.IPAMType(), stdin, vlanIf.IfName(cmdCtx.args.IfName), cmdCtx.ifIndex+1+i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// execVlanIPAM calls f for each VLAN sub-interface with IPAM configuration, the IPAM plugin
+// is called for the sub-interface of the container interface with provided name, if the name is set
+func (p *Plugin) execVlanIPAM(vlanIfs []localtypes.VlanInterface, ifName string, stdin []byte,
+	f func(plugin string, netconf []byte) error) error {
+	for i := range vlanIfs {
+		vlanIf := &vlanIfs[i]
+		if vlanIf.IPAMType() == "" {
+			continue
+		}
+		vlanStdin, err := vlanIPAMStdin(stdin, vlanIf)
+		if err != nil {
+			return err
+		}
+		vlanIfName := ""
+		if ifName != "" {
+			vlanIfName = vlanIf.IfName(ifName)
+		}
+		if err = withIfName(vlanIfName, func() error { return f(vlanIf.IPAMType(), vlanStdin) }); err != nil {
+			return fmt.Errorf("IPAM plugin %q failed for VLAN %d sub-interface: %v", vlanIf.IPAMType(), vlanIf.ID, err)
+		}
+	}
+	return nil
+}
+
+// execVlanIPAMGC runs GC for the IPAM plugins configured for VLAN sub-interfaces,
+// VLAN sub-interfaces of the valid attachments are added to the list of valid attachments
+func (p *Plugin) execVlanIPAMGC(vlanIfs []localtypes.VlanInterface, stdin []byte) error {
+	conf := map[string]interface{}{}
+	if err := json.Unmarshal(stdin, &conf); err != nil {
+		return fmt.Errorf("failed to parse network configuration: %v", err)
+	}
+	attachments, _ := conf[validAttachmentsKey].([]interface{})
+	vlanAttachments := make([]interface{}, 0, len(attachments)*(len(vlanIfs)+1))
+	for _, item := range attachments {
+		vlanAttachments = append(vlanAttachments, item)
+		attachment, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ifName, _ := attachment["ifname"].(string)
+		for i := range vlanIfs {
+			vlanAttachments = append(vlanAttachments, map[string]interface{}{
+				"containerID": attachment["containerID"],
+				"ifname":      vlanIfs[i].IfName(ifName),
+			})
+		}
+	}
+	conf[validAttachmentsKey] = vlanAttachments
+	gcStdin, err := json.Marshal(conf)
+	if err != nil {
+		return fmt.Errorf("failed to serialize network configuration: %v", err)
+	}
+	return p.execVlanIPAM(vlanIfs, "", gcStdin, p.ipam.ExecGC)
+}
+
+// vlanIPAMStdin returns the network configuration for the IPAM plugin of the VLAN sub-interface,
+// top-level ipam section is replaced with the ipam section of the sub-interface
+func vlanIPAMStdin(stdin []byte, vlanIf *localtypes.VlanInterface) ([]byte, error) {
+	conf := map[string]interface{}{}
+	if err := json.Unmarshal(stdin, &conf); err != nil {
+		return nil, fmt.Errorf("failed to parse network configuration: %v", err)
+	}
+	conf["ipam"] = vlanIf.IPAM
+	vlanStdin, err := json.Marshal(conf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize IPAM configuration for VLAN %d: %v", vlanIf.ID, err)
+	}
+	return vlanStdin, nil
+}
+
+// withIfName calls f with CNI_IFNAME set to ifName, IPAM plugins inherit CNI_IFNAME from the environment
+// and use it to distinguish allocations of the VLAN sub-interfaces from allocations of the container interface.
+// Environment is not changed if ifName is empty
+func withIfName(ifName string, f func() error) error {
+	orig, isSet := os.LookupEnv("CNI_IFNAME")
+	if ifName == "" || (isSet && orig == ifName) {
+		return f()
+	}
+	if err := os.Setenv("CNI_IFNAME", ifName); err != nil {
+		return fmt.Errorf("failed to set CNI_IFNAME: %v", err)
+	}
+	defer func() {
+		if isSet {
+			_ = os.Setenv("CNI_IFNAME", orig)
+		} else {
+			_ = os.Unsetenv("CNI_IFNAME")
+		}
+	}()
+	return f()
+}
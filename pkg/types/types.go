@@ -1,7 +1,12 @@
 package types
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/containernetworking/cni/pkg/types"
+	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/vishvananda/netlink"
 )
 
 // VfState represents the state of the VF
@@ -10,11 +15,73 @@ type VfState struct {
 	AdminMAC     string `json:"admin_mac"`
 	EffectiveMAC string `json:"effective_mac"`
 	MTU          int    `json:"mtu"`
+	SpoofChk     bool   `json:"spoofchk"`
+	Trust        bool   `json:"trust"`
+	MinTxRate    int    `json:"min_tx_rate"`
+	MaxTxRate    int    `json:"max_tx_rate"`
+	LinkState    uint32 `json:"link_state"`
+	AdminUp      bool   `json:"admin_up"`
+	Promisc      bool   `json:"promisc"`
+	Allmulti     bool   `json:"allmulti"`
+	// ethtool settings of the VF netdevice, only values for configured settings are saved
+	Ethtool *EthtoolConf `json:"ethtool,omitempty"`
+	// Saved is set when the complete VF configuration on the PF was saved during cmdAdd flow,
+	// otherwise only values for configured options are valid
+	Saved bool `json:"saved"`
+	// NetdevSaved is set when the complete VF netdevice state was saved during cmdAdd flow,
+	// otherwise only values for configured options are valid
+	NetdevSaved bool `json:"netdev_saved"`
 }
 
 // RepState represents the state of the Representor
 type RepState struct {
-	MTU int `json:"mtu"`
+	MTU     int    `json:"mtu"`
+	AdminUp bool   `json:"admin_up"`
+	Alias   string `json:"alias"`
+	// name of the device to which representor was attached
+	Master string `json:"master"`
+	// bridge port flags of the representor if its master is a bridge
+	PortFlags *netlink.Protinfo `json:"port_flags,omitempty"`
+	// Saved is set when the complete Representor state was saved during cmdAdd flow,
+	// otherwise only values for configured options are valid
+	Saved bool `json:"saved"`
+}
+
+// PortFlags represents bridge port flags of the representor, nil value means that the flag is not changed
+type PortFlags struct {
+	// learning of source MAC addresses
+	Learning *bool `json:"learning,omitempty"`
+	// flooding of unknown unicast traffic
+	Flood *bool `json:"flood,omitempty"`
+	// flooding of multicast traffic
+	McastFlood *bool `json:"mcastFlood,omitempty"`
+	// flooding of broadcast traffic
+	BcastFlood *bool `json:"bcastFlood,omitempty"`
+}
+
+// BridgePortInfo represents bridge port attributes of the link,
+// extends netlink.Protinfo with attributes which are not reported by the netlink package
+type BridgePortInfo struct {
+	netlink.Protinfo
+	McastFlood bool
+	BcastFlood bool
+	// STP path cost and priority of the port
+	Cost     uint32
+	Priority uint16
+}
+
+// FdbEntry represents static FDB entry of the bridge, VLAN 0 means entry without VLAN
+type FdbEntry struct {
+	MAC  string `json:"mac"`
+	Vlan int    `json:"vlan"`
+}
+
+// NeighEntry represents permanent neighbor entry of the bridge device or its VLAN device
+type NeighEntry struct {
+	// name of the device to which the entry belongs
+	Dev string `json:"dev"`
+	IP  string `json:"ip"`
+	MAC string `json:"mac"`
 }
 
 // Trunk represents configuration options for VLAN trunk
@@ -24,6 +91,50 @@ type Trunk struct {
 	ID    *int `json:"id,omitempty"`
 }
 
+// IfNamePlaceholder is replaced by the container interface name in sysctl keys
+// and VLAN sub-interface names
+const IfNamePlaceholder = "<if>"
+
+// EthtoolConf represents ethtool settings of the VF netdevice
+type EthtoolConf struct {
+	// features to enable or disable, key is a feature name as reported by "ethtool --show-features"
+	Features map[string]bool `json:"features,omitempty"`
+	// number of combined channels
+	CombinedChannels *int `json:"combinedChannels,omitempty"`
+	// sizes of RX and TX rings
+	Rings *EthtoolRings `json:"rings,omitempty"`
+}
+
+// EthtoolRings represents ring sizes of the VF netdevice
+type EthtoolRings struct {
+	RX *int `json:"rx,omitempty"`
+	TX *int `json:"tx,omitempty"`
+}
+
+// VlanInterface represents VLAN sub-interface created on top of the VF netdevice in the container
+type VlanInterface struct {
+	// VLAN ID, must be allowed by the trunk configuration
+	ID int `json:"id"`
+	// interface name, IfNamePlaceholder is replaced by the container interface name, default is "<if>.<id>"
+	Name string `json:"name,omitempty"`
+	// IPAM configuration for the sub-interface, same format as the top-level ipam option
+	IPAM map[string]interface{} `json:"ipam,omitempty"`
+}
+
+// IPAMType returns type of the IPAM plugin of the sub-interface, empty if IPAM is not configured
+func (v *VlanInterface) IPAMType() string {
+	ipamType, _ := v.IPAM["type"].(string)
+	return ipamType
+}
+
+// IfName returns name of the sub-interface on top of the container interface with provided name
+func (v *VlanInterface) IfName(contIfName string) string {
+	if v.Name == "" {
+		return fmt.Sprintf("%s.%d", contIfName, v.ID)
+	}
+	return strings.ReplaceAll(v.Name, IfNamePlaceholder, contIfName)
+}
+
 // NetConf extends types.NetConf for accelerated-bridge-cni
 // defines accelerated-bridge-cni public API
 type NetConf struct {
@@ -44,7 +155,48 @@ type NetConf struct {
 	// MTU for VF and representor
 	MTU int `json:"mtu"`
 	// PCI address of a VF in valid sysfs format
-	DeviceID      string `json:"deviceID"`
+	DeviceID string `json:"deviceID"`
+	// VF spoof checking, "on" or "off"
+	SpoofChk string `json:"spoofchk,omitempty"`
+	// VF trust mode, "on" or "off"
+	Trust string `json:"trust,omitempty"`
+	// VF minimal transmit rate in Mbps, 0 means no limit
+	MinTxRate *int `json:"minTxRate,omitempty"`
+	// VF maximal transmit rate in Mbps, 0 means no limit
+	MaxTxRate *int `json:"maxTxRate,omitempty"`
+	// VF link state, "auto", "enable" or "disable"
+	LinkState string `json:"linkState,omitempty"`
+	// promiscuous mode of the VF netdevice in the container, "on" or "off"
+	Promisc string `json:"promisc,omitempty"`
+	// all-multicast mode of the VF netdevice in the container, "on" or "off"
+	Allmulti string `json:"allmulti,omitempty"`
+	// ethtool settings for the VF netdevice in the container
+	Ethtool *EthtoolConf `json:"ethtool,omitempty"`
+	// interface-scoped sysctls for the VF netdevice in the container,
+	// e.g. "net.ipv6.conf.<if>.accept_ra"
+	Sysctl map[string]string `json:"sysctl,omitempty"`
+	// VLAN sub-interfaces created on top of the VF netdevice in the container for trunk VLANs
+	VlanInterfaces []VlanInterface `json:"vlanInterfaces,omitempty"`
+	// isolate the representor bridge port, isolated ports can communicate only with non-isolated ports
+	Isolated bool `json:"isolated,omitempty"`
+	// bridge port flags of the representor
+	PortFlags *PortFlags `json:"portFlags,omitempty"`
+	// add static FDB entries for the VF MAC to the representor
+	StaticFDB bool `json:"staticFdb,omitempty"`
+	// suppress ARP and ND on the representor bridge port, the bridge answers for the VF addresses
+	NeighSuppress bool `json:"neighSuppress,omitempty"`
+	// block the representor bridge port if it receives STP BPDU
+	BpduGuard bool `json:"bpduGuard,omitempty"`
+	// don't allow the representor bridge port to become the STP root port
+	RootBlock bool `json:"rootBlock,omitempty"`
+	// STP path cost of the representor bridge port
+	Cost *int `json:"cost,omitempty"`
+	// STP priority of the representor bridge port
+	Priority *int `json:"priority,omitempty"`
+	// add alternative name identifying the pod to the representor
+	RepresentorAltName bool `json:"representorAltName,omitempty"`
+	// time in seconds to wait for the VF lock
+	LockTimeout   int `json:"lockTimeout,omitempty"`
 	RuntimeConfig struct {
 		Mac               string `json:"mac,omitempty"`
 		CNIDeviceInfoFile string `json:"CNIDeviceInfoFile,omitempty"`
@@ -74,6 +226,18 @@ type PluginConf struct {
 	VFID int `json:"vfid"`
 	// VF names after in the container; used during deletion
 	ContIFNames string `json:"cont_if_names"`
+	// names of VLAN sub-interfaces created in the container; used during deletion
+	ContVlanIfNames []string `json:"cont_vlan_if_names,omitempty"`
 	// Internal presentation of VLAN Trunk config
 	Trunk []int `json:"trunk"`
+	// Alias set on the representor to identify the pod which uses the VF
+	RepAlias string `json:"rep_alias,omitempty"`
+	// Alternative name added to the representor to identify the pod which uses the VF
+	RepAltName string `json:"rep_alt_name,omitempty"`
+	// static FDB entries added to the representor; used during deletion
+	RepFdbEntries []FdbEntry `json:"rep_fdb_entries,omitempty"`
+	// neighbor entries of the VF addresses added for ARP/ND suppression; used during deletion
+	BridgeNeighbors []NeighEntry `json:"bridge_neighbors,omitempty"`
+	// Result returned by cmdAdd; used to handle repeated ADD for the same attachment
+	Result *current.Result `json:"result,omitempty"`
 }
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+	nl "github.com/vishvananda/netlink/nl"
+	"golang.org/x/sys/unix"
+
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
+)
+
+// LinkGetBridgePortInfo returns bridge port attributes of the link attached to a bridge
+func (n *NetlinkWrapper) LinkGetBridgePortInfo(link netlink.Link) (types.BridgePortInfo, error) {
+	var info types.BridgePortInfo
+	req := nl.NewNetlinkRequest(unix.RTM_GETLINK, unix.NLM_F_DUMP)
+	req.AddData(nl.NewIfInfomsg(unix.AF_BRIDGE))
+	msgs, err := req.Execute(unix.NETLINK_ROUTE, 0)
+	if err != nil {
+		return info, err
+	}
+
+	index := link.Attrs().Index
+	for _, m := range msgs {
+		ans := nl.DeserializeIfInfomsg(m)
+		if int(ans.Index) != index {
+			continue
+		}
+		attrs, parseErr := nl.ParseRouteAttr(m[ans.Len():])
+		if parseErr != nil {
+			return info, parseErr
+		}
+		for _, attr := range attrs {
+			if attr.Attr.Type != unix.IFLA_PROTINFO|unix.NLA_F_NESTED {
+				continue
+			}
+			portAttrs, nestedErr := nl.ParseRouteAttr(attr.Value)
+			if nestedErr != nil {
+				return info, nestedErr
+			}
+			parseBridgePortInfo(portAttrs, &info)
+			return info, nil
+		}
+	}
+	return info, fmt.Errorf("bridge port with index %d not found", index)
+}
+
+func parseBridgePortInfo(attrs []syscall.NetlinkRouteAttr, info *types.BridgePortInfo) {
+	for _, attr := range attrs {
+		if len(attr.Value) == 0 {
+			continue
+		}
+		flag := attr.Value[0] != 0
+		switch attr.Attr.Type {
+		case nl.IFLA_BRPORT_COST:
+			if len(attr.Value) >= 4 {
+				info.Cost = nl.NativeEndian().Uint32(attr.Value)
+			}
+		case nl.IFLA_BRPORT_PRIORITY:
+			if len(attr.Value) >= 2 {
+				info.Priority = nl.NativeEndian().Uint16(attr.Value)
+			}
+		case nl.IFLA_BRPORT_MODE:
+			info.Hairpin = flag
+		case nl.IFLA_BRPORT_GUARD:
+			info.Guard = flag
+		case nl.IFLA_BRPORT_FAST_LEAVE:
+			info.FastLeave = flag
+		case nl.IFLA_BRPORT_PROTECT:
+			info.RootBlock = flag
+		case nl.IFLA_BRPORT_LEARNING:
+			info.Learning = flag
+		case nl.IFLA_BRPORT_UNICAST_FLOOD:
+			info.Flood = flag
+		case nl.IFLA_BRPORT_PROXYARP:
+			info.ProxyArp = flag
+		case nl.IFLA_BRPORT_PROXYARP_WIFI:
+			info.ProxyArpWiFi = flag
+		case nl.IFLA_BRPORT_ISOLATED:
+			info.Isolated = flag
+		case nl.IFLA_BRPORT_NEIGH_SUPPRESS:
+			info.NeighSuppress = flag
+		case nl.IFLA_BRPORT_MCAST_FLOOD:
+			info.McastFlood = flag
+		case nl.IFLA_BRPORT_BCAST_FLOOD:
+			info.BcastFlood = flag
+		}
+	}
+}
+
+// LinkSetMcastFlood sets flooding of multicast traffic to the bridge port
+func (n *NetlinkWrapper) LinkSetMcastFlood(link netlink.Link, mode bool) error {
+	return setBridgePortAttr(link, nl.IFLA_BRPORT_MCAST_FLOOD, boolToByte(mode))
+}
+
+// LinkSetBcastFlood sets flooding of broadcast traffic to the bridge port
+func (n *NetlinkWrapper) LinkSetBcastFlood(link netlink.Link, mode bool) error {
+	return setBridgePortAttr(link, nl.IFLA_BRPORT_BCAST_FLOOD, boolToByte(mode))
+}
+
+// LinkSetPortCost sets STP path cost of the bridge port
+func (n *NetlinkWrapper) LinkSetPortCost(link netlink.Link, cost uint32) error {
+	return setBridgePortAttr(link, nl.IFLA_BRPORT_COST, nl.Uint32Attr(cost))
+}
+
+// LinkSetPortPriority sets STP priority of the bridge port
+func (n *NetlinkWrapper) LinkSetPortPriority(link netlink.Link, priority uint16) error {
+	return setBridgePortAttr(link, nl.IFLA_BRPORT_PRIORITY, nl.Uint16Attr(priority))
+}
+
+// setBridgePortAttr sets bridge port attribute of the link attached to a bridge
+func setBridgePortAttr(link netlink.Link, attr int, value []byte) error {
+	req := nl.NewNetlinkRequest(unix.RTM_SETLINK, unix.NLM_F_ACK)
+	msg := nl.NewIfInfomsg(unix.AF_BRIDGE)
+	msg.Index = int32(link.Attrs().Index)
+	req.AddData(msg)
+
+	protinfo := nl.NewRtAttr(unix.IFLA_PROTINFO|unix.NLA_F_NESTED, nil)
+	protinfo.AddRtAttr(attr, value)
+	req.AddData(protinfo)
+	_, err := req.Execute(unix.NETLINK_ROUTE, 0)
+	return err
+}
+
+func boolToByte(x bool) []byte {
+	if x {
+		return []byte{1}
+	}
+	return []byte{0}
+}
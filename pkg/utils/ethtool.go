@@ -0,0 +1,78 @@
+package utils
+
+import "github.com/safchain/ethtool"
+
+// Ethtool represents limited subset of functions from ethtool package,
+// functions operate on the interface in the current network namespace
+type Ethtool interface {
+	Features(string) (map[string]bool, error)
+	Change(string, map[string]bool) error
+	GetChannels(string) (ethtool.Channels, error)
+	SetChannels(string, ethtool.Channels) (ethtool.Channels, error)
+	GetRing(string) (ethtool.Ring, error)
+	SetRing(string, ethtool.Ring) (ethtool.Ring, error)
+}
+
+// EthtoolWrapper wrapper for ethtool package, ethtool socket is opened for every call
+// to make sure that it belongs to the current network namespace
+type EthtoolWrapper struct{}
+
+// Features is a wrapper for ethtool.Features
+func (e *EthtoolWrapper) Features(intf string) (map[string]bool, error) {
+	et, err := ethtool.NewEthtool()
+	if err != nil {
+		return nil, err
+	}
+	defer et.Close()
+	return et.Features(intf)
+}
+
+// Change is a wrapper for ethtool.Change
+func (e *EthtoolWrapper) Change(intf string, config map[string]bool) error {
+	et, err := ethtool.NewEthtool()
+	if err != nil {
+		return err
+	}
+	defer et.Close()
+	return et.Change(intf, config)
+}
+
+// GetChannels is a wrapper for ethtool.GetChannels
+func (e *EthtoolWrapper) GetChannels(intf string) (ethtool.Channels, error) {
+	et, err := ethtool.NewEthtool()
+	if err != nil {
+		return ethtool.Channels{}, err
+	}
+	defer et.Close()
+	return et.GetChannels(intf)
+}
+
+// SetChannels is a wrapper for ethtool.SetChannels
+func (e *EthtoolWrapper) SetChannels(intf string, channels ethtool.Channels) (ethtool.Channels, error) {
+	et, err := ethtool.NewEthtool()
+	if err != nil {
+		return ethtool.Channels{}, err
+	}
+	defer et.Close()
+	return et.SetChannels(intf, channels)
+}
+
+// GetRing is a wrapper for ethtool.GetRing
+func (e *EthtoolWrapper) GetRing(intf string) (ethtool.Ring, error) {
+	et, err := ethtool.NewEthtool()
+	if err != nil {
+		return ethtool.Ring{}, err
+	}
+	defer et.Close()
+	return et.GetRing(intf)
+}
+
+// SetRing is a wrapper for ethtool.SetRing
+func (e *EthtoolWrapper) SetRing(intf string, ring ethtool.Ring) (ethtool.Ring, error) {
+	et, err := ethtool.NewEthtool()
+	if err != nil {
+		return ethtool.Ring{}, err
+	}
+	defer et.Close()
+	return et.SetRing(intf, ring)
+}
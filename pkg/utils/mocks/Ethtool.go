@@ -0,0 +1,134 @@
+// Code generated by mockery v2.10.0. DO NOT EDIT.
+
+package mocks
+
+import (
+	ethtool "github.com/safchain/ethtool"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// Ethtool is an autogenerated mock type for the Ethtool type
+type Ethtool struct {
+	mock.Mock
+}
+
+// Change provides a mock function with given fields: _a0, _a1
+func (_m *Ethtool) Change(_a0 string, _a1 map[string]bool) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, map[string]bool) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Features provides a mock function with given fields: _a0
+func (_m *Ethtool) Features(_a0 string) (map[string]bool, error) {
+	ret := _m.Called(_a0)
+
+	var r0 map[string]bool
+	if rf, ok := ret.Get(0).(func(string) map[string]bool); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string]bool)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetChannels provides a mock function with given fields: _a0
+func (_m *Ethtool) GetChannels(_a0 string) (ethtool.Channels, error) {
+	ret := _m.Called(_a0)
+
+	var r0 ethtool.Channels
+	if rf, ok := ret.Get(0).(func(string) ethtool.Channels); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(ethtool.Channels)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRing provides a mock function with given fields: _a0
+func (_m *Ethtool) GetRing(_a0 string) (ethtool.Ring, error) {
+	ret := _m.Called(_a0)
+
+	var r0 ethtool.Ring
+	if rf, ok := ret.Get(0).(func(string) ethtool.Ring); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(ethtool.Ring)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetChannels provides a mock function with given fields: _a0, _a1
+func (_m *Ethtool) SetChannels(_a0 string, _a1 ethtool.Channels) (ethtool.Channels, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 ethtool.Channels
+	if rf, ok := ret.Get(0).(func(string, ethtool.Channels) ethtool.Channels); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(ethtool.Channels)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, ethtool.Channels) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SetRing provides a mock function with given fields: _a0, _a1
+func (_m *Ethtool) SetRing(_a0 string, _a1 ethtool.Ring) (ethtool.Ring, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 ethtool.Ring
+	if rf, ok := ret.Get(0).(func(string, ethtool.Ring) ethtool.Ring); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(ethtool.Ring)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, ethtool.Ring) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
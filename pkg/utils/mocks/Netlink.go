@@ -10,6 +10,8 @@ import (
 	netlink "github.com/vishvananda/netlink"
 
 	nl "github.com/vishvananda/netlink/nl"
+
+	types "github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
 )
 
 // Netlink is an autogenerated mock type for the Netlink type
@@ -17,6 +19,29 @@ type Netlink struct {
 	mock.Mock
 }
 
+// AddrList provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) AddrList(_a0 netlink.Link, _a1 int) ([]netlink.Addr, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []netlink.Addr
+	if rf, ok := ret.Get(0).(func(netlink.Link, int) []netlink.Addr); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]netlink.Addr)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(netlink.Link, int) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // BridgeVlanAdd provides a mock function with given fields: _a0, _a1, _a2, _a3, _a4, _a5
 func (_m *Netlink) BridgeVlanAdd(_a0 netlink.Link, _a1 uint16, _a2 bool, _a3 bool, _a4 bool, _a5 bool) error {
 	ret := _m.Called(_a0, _a1, _a2, _a3, _a4, _a5)
@@ -68,6 +93,34 @@ func (_m *Netlink) BridgeVlanList() (map[int32][]*nl.BridgeVlanInfo, error) {
 	return r0, r1
 }
 
+// LinkAdd provides a mock function with given fields: _a0
+func (_m *Netlink) LinkAdd(_a0 netlink.Link) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkAddAltName provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) LinkAddAltName(_a0 netlink.Link, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LinkByIndex provides a mock function with given fields: index
 func (_m *Netlink) LinkByIndex(index int) (netlink.Link, error) {
 	ret := _m.Called(index)
@@ -114,6 +167,76 @@ func (_m *Netlink) LinkByName(_a0 string) (netlink.Link, error) {
 	return r0, r1
 }
 
+// LinkDel provides a mock function with given fields: _a0
+func (_m *Netlink) LinkDel(_a0 netlink.Link) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkDelAltName provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) LinkDelAltName(_a0 netlink.Link, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkGetBridgePortInfo provides a mock function with given fields: _a0
+func (_m *Netlink) LinkGetBridgePortInfo(_a0 netlink.Link) (types.BridgePortInfo, error) {
+	ret := _m.Called(_a0)
+
+	var r0 types.BridgePortInfo
+	if rf, ok := ret.Get(0).(func(netlink.Link) types.BridgePortInfo); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(types.BridgePortInfo)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(netlink.Link) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// LinkGetProtinfo provides a mock function with given fields: _a0
+func (_m *Netlink) LinkGetProtinfo(_a0 netlink.Link) (netlink.Protinfo, error) {
+	ret := _m.Called(_a0)
+
+	var r0 netlink.Protinfo
+	if rf, ok := ret.Get(0).(func(netlink.Link) netlink.Protinfo); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(netlink.Protinfo)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(netlink.Link) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // LinkList provides a mock function with given fields:
 func (_m *Netlink) LinkList() ([]netlink.Link, error) {
 	ret := _m.Called()
@@ -137,6 +260,76 @@ func (_m *Netlink) LinkList() ([]netlink.Link, error) {
 	return r0, r1
 }
 
+// LinkSetAlias provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) LinkSetAlias(_a0 netlink.Link, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetAllmulticastOff provides a mock function with given fields: _a0
+func (_m *Netlink) LinkSetAllmulticastOff(_a0 netlink.Link) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetAllmulticastOn provides a mock function with given fields: _a0
+func (_m *Netlink) LinkSetAllmulticastOn(_a0 netlink.Link) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetBcastFlood provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) LinkSetBcastFlood(_a0 netlink.Link, _a1 bool) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, bool) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetBrNeighSuppress provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) LinkSetBrNeighSuppress(_a0 netlink.Link, _a1 bool) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, bool) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LinkSetDown provides a mock function with given fields: _a0
 func (_m *Netlink) LinkSetDown(_a0 netlink.Link) error {
 	ret := _m.Called(_a0)
@@ -151,6 +344,34 @@ func (_m *Netlink) LinkSetDown(_a0 netlink.Link) error {
 	return r0
 }
 
+// LinkSetFlood provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) LinkSetFlood(_a0 netlink.Link, _a1 bool) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, bool) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetGuard provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) LinkSetGuard(_a0 netlink.Link, _a1 bool) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, bool) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LinkSetHardwareAddr provides a mock function with given fields: _a0, _a1
 func (_m *Netlink) LinkSetHardwareAddr(_a0 netlink.Link, _a1 net.HardwareAddr) error {
 	ret := _m.Called(_a0, _a1)
@@ -165,6 +386,34 @@ func (_m *Netlink) LinkSetHardwareAddr(_a0 netlink.Link, _a1 net.HardwareAddr) e
 	return r0
 }
 
+// LinkSetIsolated provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) LinkSetIsolated(_a0 netlink.Link, _a1 bool) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, bool) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetLearning provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) LinkSetLearning(_a0 netlink.Link, _a1 bool) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, bool) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LinkSetMTU provides a mock function with given fields: _a0, _a1
 func (_m *Netlink) LinkSetMTU(_a0 netlink.Link, _a1 int) error {
 	ret := _m.Called(_a0, _a1)
@@ -193,6 +442,20 @@ func (_m *Netlink) LinkSetMaster(_a0 netlink.Link, _a1 netlink.Link) error {
 	return r0
 }
 
+// LinkSetMcastFlood provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) LinkSetMcastFlood(_a0 netlink.Link, _a1 bool) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, bool) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LinkSetName provides a mock function with given fields: _a0, _a1
 func (_m *Netlink) LinkSetName(_a0 netlink.Link, _a1 string) error {
 	ret := _m.Called(_a0, _a1)
@@ -235,6 +498,90 @@ func (_m *Netlink) LinkSetNsFd(_a0 netlink.Link, _a1 int) error {
 	return r0
 }
 
+// LinkSetPortCost provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) LinkSetPortCost(_a0 netlink.Link, _a1 uint32) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, uint32) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetPortPriority provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) LinkSetPortPriority(_a0 netlink.Link, _a1 uint16) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, uint16) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetPromiscOff provides a mock function with given fields: _a0
+func (_m *Netlink) LinkSetPromiscOff(_a0 netlink.Link) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetPromiscOn provides a mock function with given fields: _a0
+func (_m *Netlink) LinkSetPromiscOn(_a0 netlink.Link) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetProtinfo provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) LinkSetProtinfo(_a0 netlink.Link, _a1 netlink.Protinfo) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, netlink.Protinfo) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetRootBlock provides a mock function with given fields: _a0, _a1
+func (_m *Netlink) LinkSetRootBlock(_a0 netlink.Link, _a1 bool) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, bool) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // LinkSetUp provides a mock function with given fields: _a0
 func (_m *Netlink) LinkSetUp(_a0 netlink.Link) error {
 	ret := _m.Called(_a0)
@@ -262,3 +609,87 @@ func (_m *Netlink) LinkSetVfHardwareAddr(_a0 netlink.Link, _a1 int, _a2 net.Hard
 
 	return r0
 }
+
+// LinkSetVfRate provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *Netlink) LinkSetVfRate(_a0 netlink.Link, _a1 int, _a2 int, _a3 int) error {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int, int, int) error); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetVfSpoofchk provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Netlink) LinkSetVfSpoofchk(_a0 netlink.Link, _a1 int, _a2 bool) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int, bool) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetVfState provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Netlink) LinkSetVfState(_a0 netlink.Link, _a1 int, _a2 uint32) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int, uint32) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// LinkSetVfTrust provides a mock function with given fields: _a0, _a1, _a2
+func (_m *Netlink) LinkSetVfTrust(_a0 netlink.Link, _a1 int, _a2 bool) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(netlink.Link, int, bool) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NeighDel provides a mock function with given fields: _a0
+func (_m *Netlink) NeighDel(_a0 *netlink.Neigh) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*netlink.Neigh) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NeighSet provides a mock function with given fields: _a0
+func (_m *Netlink) NeighSet(_a0 *netlink.Neigh) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*netlink.Neigh) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
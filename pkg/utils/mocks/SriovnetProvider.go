@@ -2,13 +2,37 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	sriovnet "github.com/Mellanox/sriovnet"
+	mock "github.com/stretchr/testify/mock"
+)
 
 // SriovnetProvider is an autogenerated mock type for the SriovnetProvider type
 type SriovnetProvider struct {
 	mock.Mock
 }
 
+// GetRepresentorPortFlavour provides a mock function with given fields: _a0
+func (_m *SriovnetProvider) GetRepresentorPortFlavour(_a0 string) (sriovnet.PortFlavour, error) {
+	ret := _m.Called(_a0)
+
+	var r0 sriovnet.PortFlavour
+	if rf, ok := ret.Get(0).(func(string) sriovnet.PortFlavour); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(sriovnet.PortFlavour)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUplinkRepresentor provides a mock function with given fields: _a0
 func (_m *SriovnetProvider) GetUplinkRepresentor(_a0 string) (string, error) {
 	ret := _m.Called(_a0)
@@ -6,6 +6,8 @@ import (
 
 	"github.com/vishvananda/netlink"
 	nl "github.com/vishvananda/netlink/nl"
+
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
 )
 
 const (
@@ -18,6 +20,34 @@ type Netlink interface {
 	LinkByName(string) (netlink.Link, error)
 	LinkByIndex(index int) (netlink.Link, error)
 	LinkSetVfHardwareAddr(netlink.Link, int, net.HardwareAddr) error
+	LinkSetVfSpoofchk(netlink.Link, int, bool) error
+	LinkSetVfTrust(netlink.Link, int, bool) error
+	LinkSetVfRate(netlink.Link, int, int, int) error
+	LinkSetVfState(netlink.Link, int, uint32) error
+	LinkSetPromiscOn(netlink.Link) error
+	LinkSetPromiscOff(netlink.Link) error
+	LinkSetAllmulticastOn(netlink.Link) error
+	LinkSetAllmulticastOff(netlink.Link) error
+	LinkAdd(netlink.Link) error
+	LinkDel(netlink.Link) error
+	LinkSetAlias(netlink.Link, string) error
+	LinkAddAltName(netlink.Link, string) error
+	LinkDelAltName(netlink.Link, string) error
+	LinkGetProtinfo(netlink.Link) (netlink.Protinfo, error)
+	LinkSetProtinfo(netlink.Link, netlink.Protinfo) error
+	LinkSetIsolated(netlink.Link, bool) error
+	LinkSetBrNeighSuppress(netlink.Link, bool) error
+	LinkSetLearning(netlink.Link, bool) error
+	LinkSetFlood(netlink.Link, bool) error
+	LinkSetMcastFlood(netlink.Link, bool) error
+	LinkSetBcastFlood(netlink.Link, bool) error
+	LinkSetGuard(netlink.Link, bool) error
+	LinkSetRootBlock(netlink.Link, bool) error
+	LinkSetPortCost(netlink.Link, uint32) error
+	LinkSetPortPriority(netlink.Link, uint16) error
+	LinkGetBridgePortInfo(netlink.Link) (types.BridgePortInfo, error)
+	NeighSet(*netlink.Neigh) error
+	NeighDel(*netlink.Neigh) error
 	LinkSetHardwareAddr(netlink.Link, net.HardwareAddr) error
 	LinkSetUp(netlink.Link) error
 	LinkSetDown(netlink.Link) error
@@ -30,6 +60,7 @@ type Netlink interface {
 	LinkSetMTU(netlink.Link, int) error
 	BridgeVlanList() (map[int32][]*nl.BridgeVlanInfo, error)
 	LinkList() ([]netlink.Link, error)
+	AddrList(netlink.Link, int) ([]netlink.Addr, error)
 }
 
 // NetlinkWrapper wrapper for netlink package
@@ -51,6 +82,26 @@ func (n *NetlinkWrapper) LinkSetVfHardwareAddr(link netlink.Link, vf int, hwaddr
 	return netlink.LinkSetVfHardwareAddr(link, vf, hwaddr)
 }
 
+// LinkSetVfSpoofchk is a wrapper for netlink.LinkSetVfSpoofchk
+func (n *NetlinkWrapper) LinkSetVfSpoofchk(link netlink.Link, vf int, check bool) error {
+	return netlink.LinkSetVfSpoofchk(link, vf, check)
+}
+
+// LinkSetVfTrust is a wrapper for netlink.LinkSetVfTrust
+func (n *NetlinkWrapper) LinkSetVfTrust(link netlink.Link, vf int, state bool) error {
+	return netlink.LinkSetVfTrust(link, vf, state)
+}
+
+// LinkSetVfRate is a wrapper for netlink.LinkSetVfRate
+func (n *NetlinkWrapper) LinkSetVfRate(link netlink.Link, vf, minRate, maxRate int) error {
+	return netlink.LinkSetVfRate(link, vf, minRate, maxRate)
+}
+
+// LinkSetVfState is a wrapper for netlink.LinkSetVfState
+func (n *NetlinkWrapper) LinkSetVfState(link netlink.Link, vf int, state uint32) error {
+	return netlink.LinkSetVfState(link, vf, state)
+}
+
 // LinkSetHardwareAddr is a wrapper for netlink.LinkSetHardwareAddr
 func (n *NetlinkWrapper) LinkSetHardwareAddr(link netlink.Link, hwaddr net.HardwareAddr) error {
 	return netlink.LinkSetHardwareAddr(link, hwaddr)
@@ -111,6 +162,126 @@ func (n *NetlinkWrapper) LinkList() ([]netlink.Link, error) {
 	return netlink.LinkList()
 }
 
+// AddrList is a wrapper for netlink.AddrList
+func (n *NetlinkWrapper) AddrList(link netlink.Link, family int) ([]netlink.Addr, error) {
+	return netlink.AddrList(link, family)
+}
+
+// LinkSetPromiscOn is a wrapper for netlink.SetPromiscOn
+func (n *NetlinkWrapper) LinkSetPromiscOn(link netlink.Link) error {
+	return netlink.SetPromiscOn(link)
+}
+
+// LinkSetPromiscOff is a wrapper for netlink.SetPromiscOff
+func (n *NetlinkWrapper) LinkSetPromiscOff(link netlink.Link) error {
+	return netlink.SetPromiscOff(link)
+}
+
+// LinkSetAllmulticastOn is a wrapper for netlink.LinkSetAllmulticastOn
+func (n *NetlinkWrapper) LinkSetAllmulticastOn(link netlink.Link) error {
+	return netlink.LinkSetAllmulticastOn(link)
+}
+
+// LinkSetAllmulticastOff is a wrapper for netlink.LinkSetAllmulticastOff
+func (n *NetlinkWrapper) LinkSetAllmulticastOff(link netlink.Link) error {
+	return netlink.LinkSetAllmulticastOff(link)
+}
+
+// LinkAdd is a wrapper for netlink.LinkAdd
+func (n *NetlinkWrapper) LinkAdd(link netlink.Link) error {
+	return netlink.LinkAdd(link)
+}
+
+// LinkDel is a wrapper for netlink.LinkDel
+func (n *NetlinkWrapper) LinkDel(link netlink.Link) error {
+	return netlink.LinkDel(link)
+}
+
+// LinkSetAlias is a wrapper for netlink.LinkSetAlias
+func (n *NetlinkWrapper) LinkSetAlias(link netlink.Link, name string) error {
+	return netlink.LinkSetAlias(link, name)
+}
+
+// LinkAddAltName is a wrapper for netlink.LinkAddAltName
+func (n *NetlinkWrapper) LinkAddAltName(link netlink.Link, name string) error {
+	return netlink.LinkAddAltName(link, name)
+}
+
+// LinkDelAltName is a wrapper for netlink.LinkDelAltName
+func (n *NetlinkWrapper) LinkDelAltName(link netlink.Link, name string) error {
+	return netlink.LinkDelAltName(link, name)
+}
+
+// LinkGetProtinfo is a wrapper for netlink.LinkGetProtinfo
+func (n *NetlinkWrapper) LinkGetProtinfo(link netlink.Link) (netlink.Protinfo, error) {
+	return netlink.LinkGetProtinfo(link)
+}
+
+// LinkSetProtinfo sets bridge port flags of the link from Protinfo
+func (n *NetlinkWrapper) LinkSetProtinfo(link netlink.Link, protinfo netlink.Protinfo) error {
+	setters := []struct {
+		set  func(netlink.Link, bool) error
+		mode bool
+	}{
+		{netlink.LinkSetHairpin, protinfo.Hairpin},
+		{netlink.LinkSetGuard, protinfo.Guard},
+		{netlink.LinkSetFastLeave, protinfo.FastLeave},
+		{netlink.LinkSetRootBlock, protinfo.RootBlock},
+		{netlink.LinkSetLearning, protinfo.Learning},
+		{netlink.LinkSetFlood, protinfo.Flood},
+		{netlink.LinkSetBrProxyArp, protinfo.ProxyArp},
+		{netlink.LinkSetBrProxyArpWiFi, protinfo.ProxyArpWiFi},
+		{netlink.LinkSetIsolated, protinfo.Isolated},
+		{netlink.LinkSetBrNeighSuppress, protinfo.NeighSuppress},
+	}
+	for _, s := range setters {
+		if err := s.set(link, s.mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LinkSetIsolated is a wrapper for netlink.LinkSetIsolated
+func (n *NetlinkWrapper) LinkSetIsolated(link netlink.Link, mode bool) error {
+	return netlink.LinkSetIsolated(link, mode)
+}
+
+// LinkSetBrNeighSuppress is a wrapper for netlink.LinkSetBrNeighSuppress
+func (n *NetlinkWrapper) LinkSetBrNeighSuppress(link netlink.Link, mode bool) error {
+	return netlink.LinkSetBrNeighSuppress(link, mode)
+}
+
+// LinkSetLearning is a wrapper for netlink.LinkSetLearning
+func (n *NetlinkWrapper) LinkSetLearning(link netlink.Link, mode bool) error {
+	return netlink.LinkSetLearning(link, mode)
+}
+
+// LinkSetFlood is a wrapper for netlink.LinkSetFlood
+func (n *NetlinkWrapper) LinkSetFlood(link netlink.Link, mode bool) error {
+	return netlink.LinkSetFlood(link, mode)
+}
+
+// LinkSetGuard is a wrapper for netlink.LinkSetGuard
+func (n *NetlinkWrapper) LinkSetGuard(link netlink.Link, mode bool) error {
+	return netlink.LinkSetGuard(link, mode)
+}
+
+// LinkSetRootBlock is a wrapper for netlink.LinkSetRootBlock
+func (n *NetlinkWrapper) LinkSetRootBlock(link netlink.Link, mode bool) error {
+	return netlink.LinkSetRootBlock(link, mode)
+}
+
+// NeighSet is a wrapper for netlink.NeighSet
+func (n *NetlinkWrapper) NeighSet(neigh *netlink.Neigh) error {
+	return netlink.NeighSet(neigh)
+}
+
+// NeighDel is a wrapper for netlink.NeighDel
+func (n *NetlinkWrapper) NeighDel(neigh *netlink.Neigh) error {
+	return netlink.NeighDel(neigh)
+}
+
 // BridgePVIDVlanAdd configure port VLAN id for link
 func BridgePVIDVlanAdd(nlink Netlink, link netlink.Link, vlanID int) error {
 	// pvid, egress untagged
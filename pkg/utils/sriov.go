@@ -6,6 +6,7 @@ import "github.com/Mellanox/sriovnet"
 type SriovnetProvider interface {
 	GetVfRepresentor(string, int) (string, error)
 	GetUplinkRepresentor(string) (string, error)
+	GetRepresentorPortFlavour(string) (sriovnet.PortFlavour, error)
 }
 
 type SriovnetWrapper struct{}
@@ -17,3 +18,7 @@ func (s *SriovnetWrapper) GetVfRepresentor(master string, vfid int) (string, err
 func (s *SriovnetWrapper) GetUplinkRepresentor(vfPciAddress string) (string, error) {
 	return sriovnet.GetUplinkRepresentor(vfPciAddress)
 }
+
+func (s *SriovnetWrapper) GetRepresentorPortFlavour(netdev string) (sriovnet.PortFlavour, error) {
+	return sriovnet.GetRepresentorPortFlavour(netdev)
+}
@@ -0,0 +1,18 @@
+package utils
+
+import "github.com/containernetworking/plugins/pkg/utils/sysctl"
+
+// Sysctl represents functions to write kernel parameters
+// of the current network namespace
+type Sysctl interface {
+	Set(string, string) error
+}
+
+// SysctlWrapper wrapper for sysctl package
+type SysctlWrapper struct{}
+
+// Set sets value of the kernel parameter
+func (s *SysctlWrapper) Set(name, value string) error {
+	_, err := sysctl.Sysctl(name, value)
+	return err
+}
@@ -3,12 +3,15 @@ package utils
 import (
 	"errors"
 	"net"
+	"syscall"
 
 	"github.com/vishvananda/netlink"
+	nl "github.com/vishvananda/netlink/nl"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/types"
 	"github.com/k8snetworkplumbingwg/accelerated-bridge-cni/pkg/utils/mocks"
 )
 
@@ -197,6 +200,39 @@ var _ = Describe("Utils", func() {
 			Expect(bd.Attrs().Name).To(BeEquivalentTo(expectedBondName))
 		})
 	})
+	Context("Checking parseBridgePortInfo function", func() {
+		It("Parses bridge port flags", func() {
+			attrs := []syscall.NetlinkRouteAttr{
+				{Attr: syscall.RtAttr{Type: nl.IFLA_BRPORT_LEARNING}, Value: []byte{1}},
+				{Attr: syscall.RtAttr{Type: nl.IFLA_BRPORT_UNICAST_FLOOD}, Value: []byte{0}},
+				{Attr: syscall.RtAttr{Type: nl.IFLA_BRPORT_ISOLATED}, Value: []byte{1}},
+				{Attr: syscall.RtAttr{Type: nl.IFLA_BRPORT_MCAST_FLOOD}, Value: []byte{1}},
+				{Attr: syscall.RtAttr{Type: nl.IFLA_BRPORT_BCAST_FLOOD}, Value: []byte{0}},
+				{Attr: syscall.RtAttr{Type: nl.IFLA_BRPORT_STATE}, Value: []byte{3}},
+			}
+			info := types.BridgePortInfo{}
+			parseBridgePortInfo(attrs, &info)
+			Expect(info).To(Equal(types.BridgePortInfo{
+				Protinfo:   netlink.Protinfo{Learning: true, Isolated: true},
+				McastFlood: true,
+			}))
+		})
+		It("Parses STP attributes", func() {
+			attrs := []syscall.NetlinkRouteAttr{
+				{Attr: syscall.RtAttr{Type: nl.IFLA_BRPORT_GUARD}, Value: []byte{1}},
+				{Attr: syscall.RtAttr{Type: nl.IFLA_BRPORT_PROTECT}, Value: []byte{1}},
+				{Attr: syscall.RtAttr{Type: nl.IFLA_BRPORT_COST}, Value: nl.Uint32Attr(100)},
+				{Attr: syscall.RtAttr{Type: nl.IFLA_BRPORT_PRIORITY}, Value: nl.Uint16Attr(32)},
+			}
+			info := types.BridgePortInfo{}
+			parseBridgePortInfo(attrs, &info)
+			Expect(info).To(Equal(types.BridgePortInfo{
+				Protinfo: netlink.Protinfo{Guard: true, RootBlock: true},
+				Cost:     100,
+				Priority: 32,
+			}))
+		})
+	})
 	Context("Checking GetBridgeLinks function", func() {
 		var (
 			nLinkMock *mocks.Netlink